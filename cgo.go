@@ -0,0 +1,6 @@
+package main
+
+// Plugins are built with cgo, which requires the main package to import "C". go build skips this file when cgo is
+// disabled, so that the command line tool can still be built with go-ora only.
+
+import "C"
@@ -16,7 +16,7 @@ import (
 
 var clientSchema = func(testID string) string {
 	s := fmt.Sprintf(`CREATE TABLE %s (
-	ID      		varchar(255) NOT NULL PRIMARY KEY,
+	ID      		varchar(255) NOT NULL PRIMARY KEY%s,
 	CLIENT_NAME		VARCHAR2 (4000) NULL,
 	CLIENT_SECRET  	VARCHAR2 (4000) NULL,
 	REDIRECT_URIS  	VARCHAR2 (4000) NULL,
@@ -30,10 +30,45 @@ var clientSchema = func(testID string) string {
 	LOGO_URI  		VARCHAR2 (4000) NULL,
 	CONTACTS  		VARCHAR2 (4000) NULL,
 	IS_PUBLIC  		CHAR(1 BYTE) NOT NULL
-)`, testID)
+)%s`, testID, constraintStorage(), tableStorage())
 	return s
 }
 
+var clientMigrations = func(table string) []*Migration {
+	return []*Migration{
+		{
+			Version: 1,
+			Up:      []string{clientSchema(table)},
+			Down:    []string{fmt.Sprintf("DROP TABLE %s", table)},
+		},
+	}
+}
+
+var clientTables = func(table string) []TableDefinition {
+	return []TableDefinition{
+		{
+			Name: table,
+			Columns: []ColumnDefinition{
+				{"ID", "VARCHAR2", 255, false},
+				{"CLIENT_NAME", "VARCHAR2", 4000, true},
+				{"CLIENT_SECRET", "VARCHAR2", 4000, true},
+				{"REDIRECT_URIS", "VARCHAR2", 4000, true},
+				{"GRANT_TYPES", "VARCHAR2", 4000, true},
+				{"RESPONSE_TYPES", "VARCHAR2", 4000, true},
+				{"SCOPE", "VARCHAR2", 4000, true},
+				{"OWNER", "VARCHAR2", 4000, true},
+				{"POLICY_URI", "VARCHAR2", 4000, true},
+				{"TOS_URI", "VARCHAR2", 4000, true},
+				{"CLIENT_URI", "VARCHAR2", 4000, true},
+				{"LOGO_URI", "VARCHAR2", 4000, true},
+				{"CONTACTS", "VARCHAR2", 4000, true},
+				{"IS_PUBLIC", "CHAR", 1, false},
+			},
+			PrimaryKey: []string{"ID"},
+		},
+	}
+}
+
 type ClientManager struct {
 	Hasher fosite.Hasher
 	DB     *sqlx.DB
@@ -112,37 +147,60 @@ func (d *clientSqlData) ToClient() *client.Client {
 	}
 }
 
+func (m *ClientManager) MigrationSet() *MigrationSet {
+	return &MigrationSet{
+		Manager:    "client",
+		Table:      m.GetTable(),
+		Migrations: clientMigrations(m.GetTable()),
+		Tables:     clientTables(m.GetTable()),
+	}
+}
+
 func (m *ClientManager) CreateSchemas() (int, error) {
-	if _, err := m.DB.Exec(clientSchema(m.GetTable())); err != nil {
-		return 0, errors.Wrap(err, "Could not migrate client sql clientSchema")
+	n, err := (&Migrator{DB: m.DB}).Up(m.MigrationSet())
+	if err != nil {
+		return n, errors.Wrap(err, "Could not migrate client sql clientSchema")
 	}
-	return 1, nil
+	return n, nil
 }
 
 func (m *ClientManager) GetTable() string {
 	if m.Table == "" {
-		return "hydcl"
+		return tableName(configTableClient)
 	}
 	return m.Table
 }
 
 func (m *ClientManager) GetConcreteClient(ID string) (*client.Client, error) {
+	return m.getConcreteClient(context.Background(), ID)
+}
+
+func (m *ClientManager) getConcreteClient(ctx context.Context, ID string) (*client.Client, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
 	var d clientSqlData
-	if err := m.DB.Get(&d, m.DB.Rebind(fmt.Sprintf("SELECT * FROM %s WHERE ID=?", m.GetTable())), ID); err == sql.ErrNoRows {
+	query := m.DB.Rebind(fmt.Sprintf("SELECT * FROM %s WHERE ID=?", m.GetTable()))
+	if err := retryTransient(ctx, m.DB, func(conn *dbConn) error {
+		return conn.GetContext(ctx, &d, query, ID)
+	}); err == sql.ErrNoRows {
 		return nil, errors.Wrap(pkg.ErrNotFound, "")
 	} else if err != nil {
-		return nil, errors.WithStack(err)
+		return nil, classifyError(err)
 	}
 
 	return d.ToClient(), nil
 }
 
-func (m *ClientManager) GetClient(_ context.Context, ID string) (fosite.Client, error) {
-	return m.GetConcreteClient(ID)
+func (m *ClientManager) GetClient(ctx context.Context, ID string) (fosite.Client, error) {
+	return m.getConcreteClient(ctx, ID)
 }
 
 func (m *ClientManager) UpdateClient(c *client.Client) error {
-	o, err := m.GetClient(context.Background(), c.ID)
+	ctx, cancel := queryContext(context.Background())
+	defer cancel()
+
+	o, err := m.GetClient(ctx, c.ID)
 	if err != nil {
 		return errors.WithStack(err)
 	}
@@ -163,8 +221,8 @@ func (m *ClientManager) UpdateClient(c *client.Client) error {
 		update = append(update, fmt.Sprintf("%s=:%s", param, param))
 	}
 
-	if _, err := m.DB.NamedExec(fmt.Sprintf(`UPDATE %s SET %s WHERE ID=:ID`, m.GetTable(), strings.Join(update, ", ")), s); err != nil {
-		return errors.WithStack(err)
+	if _, err := m.DB.NamedExecContext(ctx, fmt.Sprintf(`UPDATE %s SET %s WHERE ID=:ID`, m.GetTable(), strings.Join(update, ", ")), s); err != nil {
+		return classifyError(err)
 	}
 	return nil
 }
@@ -193,6 +251,9 @@ func (m *ClientManager) CreateClient(c *client.Client) error {
 	}
 	c.Secret = string(h)
 
+	ctx, cancel := queryContext(context.Background())
+	defer cancel()
+
 	data := clientSqlDataFromClient(c)
 	query := fmt.Sprintf(
 		"INSERT INTO %s (%s) VALUES (%s)",
@@ -200,15 +261,18 @@ func (m *ClientManager) CreateClient(c *client.Client) error {
 		strings.Join(clientSqlParams, ", "),
 		":"+strings.Join(clientSqlParams, ", :"),
 	)
-	if _, err := m.DB.NamedExec(query, data); err != nil {
-		return errors.WithStack(err)
+	if _, err := m.DB.NamedExecContext(ctx, query, data); err != nil {
+		return classifyError(err)
 	}
 	return nil
 }
 
 func (m *ClientManager) DeleteClient(ID string) error {
-	if _, err := m.DB.Exec(m.DB.Rebind(fmt.Sprintf(`DELETE FROM %s WHERE ID=?`, m.GetTable())), ID); err != nil {
-		return errors.WithStack(err)
+	ctx, cancel := queryContext(context.Background())
+	defer cancel()
+
+	if _, err := m.DB.ExecContext(ctx, m.DB.Rebind(fmt.Sprintf(`DELETE FROM %s WHERE ID=?`, m.GetTable())), ID); err != nil {
+		return classifyError(err)
 	}
 	return nil
 }
@@ -217,8 +281,15 @@ func (m *ClientManager) GetClients() (clients map[string]client.Client, err erro
 	var d = []clientSqlData{}
 	clients = make(map[string]client.Client)
 
-	if err := m.DB.Select(&d, fmt.Sprintf("SELECT * FROM %s", m.GetTable())); err != nil {
-		return nil, errors.WithStack(err)
+	ctx, cancel := queryContext(context.Background())
+	defer cancel()
+
+	query := fmt.Sprintf("SELECT * FROM %s", m.GetTable())
+	if err := retryTransient(ctx, m.DB, func(conn *dbConn) error {
+		d = d[:0]
+		return conn.SelectContext(ctx, &d, query)
+	}); err != nil {
+		return nil, classifyError(err)
 	}
 
 	for _, k := range d {
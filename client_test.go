@@ -9,6 +9,7 @@ import (
 	_ "github.com/lib/pq"
 	"github.com/ory/fosite"
 	"github.com/ory/hydra/client"
+	"github.com/pkg/errors"
 )
 
 var clientManager *ClientManager
@@ -37,3 +38,14 @@ func TestCreateGetDeleteClient(t *testing.T) {
 func TestAuthenticateClient(t *testing.T) {
 	client.TestHelperClientAuthenticate("ora", clientManager)(t)
 }
+
+func TestCreateClientConflict(t *testing.T) {
+	c := &client.Client{ID: randomTableName("conflict"), Secret: "secret"}
+	if err := clientManager.CreateClient(c); err != nil {
+		t.Fatalf("Could not create client: %s", err)
+	}
+
+	if err := clientManager.CreateClient(&client.Client{ID: c.ID, Secret: "secret"}); errors.Cause(err) != ErrConflict {
+		t.Fatalf("Expected ErrConflict but got %v", err)
+	}
+}
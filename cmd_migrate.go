@@ -3,27 +3,58 @@ package main
 import (
 	"fmt"
 	"log"
+	"time"
 
+	"github.com/jmoiron/sqlx"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 // migrateCmd represents the migrate command
 var migrateCmd = &cobra.Command{
-	Use: "migrate <oracle-url>",
-	Run: func(cmd *cobra.Command, args []string) {
-		if len(args) != 1 {
-			fmt.Println(cmd.UsageString())
-			return
-		}
+	Use:   "migrate <oracle-url>",
+	Short: "Manages the Oracle schema of all managers",
+	Long: `Manages the Oracle schema of all managers. Schema changes are shipped as numbered migrations per
+manager (client, group, jwk, oauth2, policy). Applied versions are recorded in a bookkeeping table.
+
+Running "migrate <oracle-url>" without a sub command is the same as running "migrate up <oracle-url>".`,
+	Run: runMigrateUp,
+}
+
+// selectedMigrationSets returns the migration sets of the managers given by the --manager flag, or the sets of
+// all managers if the flag is not set.
+func selectedMigrationSets(cmd *cobra.Command, db *sqlx.DB) []*MigrationSet {
+	managers, _ := cmd.Flags().GetStringSlice("manager")
+	if len(managers) == 0 {
+		return MigrationSets(db)
+	}
 
-		if db, err := Connect(args[0]); err != nil {
-			log.Fatalf("Could not connect to database because: %s", err)
-		} else if err = CreateSchemas(db); err != nil {
-			log.Fatalf("Could not create schemas because: %s", err)
+	var sets []*MigrationSet
+	for _, name := range managers {
+		var found bool
+		for _, set := range MigrationSets(db) {
+			if set.Manager == name {
+				sets = append(sets, set)
+				found = true
+			}
 		}
-	},
+		if !found {
+			log.Fatalf("Unknown manager %s, expected one of client, group, jwk, oauth2, policy", name)
+		}
+	}
+	return sets
+}
+
+func printMigrations(action string, set *MigrationSet, migrations []*Migration) {
+	for _, migration := range migrations {
+		fmt.Printf("%s migration %d of %s schema (%s)\n", action, migration.Version, set.Manager, set.Table)
+	}
 }
 
 func init() {
 	RootCmd.AddCommand(migrateCmd)
+
+	migrateCmd.PersistentFlags().StringSlice("manager", []string{}, "Restricts the command to the given managers (client, group, jwk, oauth2, policy)")
+	migrateCmd.PersistentFlags().Duration("lock-timeout", time.Minute, "Time to wait for the migration lock held by other processes")
+	viper.BindPFlag(configMigrateLockTimeout, migrateCmd.PersistentFlags().Lookup("lock-timeout"))
 }
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// migrateDownCmd represents the migrate down command
+var migrateDownCmd = &cobra.Command{
+	Use:   "down [n] <oracle-url>",
+	Short: "Reverts the last n applied migrations of every manager",
+	Long: `Reverts the last n (default 1) applied migrations of every manager, newest first. Managers are
+reverted in reverse migration order and the command stops at the first migration which can not be reverted.
+
+Use --manager to revert the migrations of selected managers only.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		steps := 1
+		switch len(args) {
+		case 1:
+		case 2:
+			n, err := strconv.Atoi(args[0])
+			if err != nil || n < 1 {
+				log.Fatalf("Expected a positive number of migrations to revert but got: %s", args[0])
+			}
+			steps = n
+			args = args[1:]
+		default:
+			fmt.Println(cmd.UsageString())
+			return
+		}
+
+		db, err := Connect(args[0])
+		if err != nil {
+			log.Fatalf("Could not connect to database because: %s", err)
+		}
+
+		m := &Migrator{DB: db}
+		sets := selectedMigrationSets(cmd, db)
+		if err := m.WithLock(func() error {
+			for i := len(sets) - 1; i >= 0; i-- {
+				reverted, err := m.Down(sets[i], steps)
+				printMigrations("Reverted", sets[i], reverted)
+				if err != nil {
+					return errors.Wrapf(err, "Could not revert %s schema", sets[i].Manager)
+				}
+			}
+			return nil
+		}); err != nil {
+			log.Fatalf("Could not revert migrations because: %s", err)
+		}
+	},
+}
+
+func init() {
+	migrateCmd.AddCommand(migrateDownCmd)
+}
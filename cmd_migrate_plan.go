@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+// migratePlanCmd represents the migrate plan command
+var migratePlanCmd = &cobra.Command{
+	Use:   "plan [version] <oracle-url>",
+	Short: "Prints the statements a migration would execute without executing them",
+	Long: `Prints the statements "migrate up" would execute as a SQL*Plus script, without executing them. If a
+version is given, the statements "migrate to <version>" would execute are printed instead.
+
+The database is only queried for the applied migrations, so the printed script reflects the current state of the
+schema. The script includes the statements maintaining the bookkeeping table.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		version := -1
+		switch len(args) {
+		case 1:
+		case 2:
+			v, err := strconv.Atoi(args[0])
+			if err != nil || v < 0 {
+				log.Fatalf("Expected a schema version but got: %s", args[0])
+			}
+			version = v
+			args = args[1:]
+		default:
+			fmt.Println(cmd.UsageString())
+			return
+		}
+
+		db, err := Connect(args[0])
+		if err != nil {
+			log.Fatalf("Could not connect to database because: %s", err)
+		}
+
+		m := &Migrator{DB: db, Plan: os.Stdout}
+		sets := selectedMigrationSets(cmd, db)
+		if version < 0 {
+			for _, set := range sets {
+				if _, err := m.Up(set); err != nil {
+					log.Fatalf("Could not plan migration of %s schema because: %s", set.Manager, err)
+				}
+			}
+			return
+		}
+
+		for i := len(sets) - 1; i >= 0; i-- {
+			if _, _, err := m.To(sets[i], version); err != nil {
+				log.Fatalf("Could not plan migration of %s schema to version %d because: %s", sets[i].Manager, version, err)
+			}
+		}
+	},
+}
+
+func init() {
+	migrateCmd.AddCommand(migratePlanCmd)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// migrateStatusCmd represents the migrate status command
+var migrateStatusCmd = &cobra.Command{
+	Use:   "status <oracle-url>",
+	Short: "Lists applied and pending migrations of every manager",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			fmt.Println(cmd.UsageString())
+			return
+		}
+
+		db, err := Connect(args[0])
+		if err != nil {
+			log.Fatalf("Could not connect to database because: %s", err)
+		}
+
+		m := &Migrator{DB: db}
+		w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(w, "MANAGER\tTABLE\tVERSION\tSTATUS\tAPPLIED AT")
+		for _, set := range selectedMigrationSets(cmd, db) {
+			status, err := m.Status(set)
+			if err != nil {
+				log.Fatalf("Could not fetch migration status of %s schema because: %s", set.Manager, err)
+			}
+
+			for _, s := range status {
+				if s.AppliedAt == nil {
+					fmt.Fprintf(w, "%s\t%s\t%d\tpending\t\n", set.Manager, set.Table, s.Migration.Version)
+				} else {
+					fmt.Fprintf(w, "%s\t%s\t%d\tapplied\t%s\n", set.Manager, set.Table, s.Migration.Version, s.AppliedAt.Format("2006-01-02 15:04:05"))
+				}
+			}
+		}
+		w.Flush()
+	},
+}
+
+func init() {
+	migrateCmd.AddCommand(migrateStatusCmd)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// migrateToCmd represents the migrate to command
+var migrateToCmd = &cobra.Command{
+	Use:   "to <version> <oracle-url>",
+	Short: "Migrates every manager to the given schema version",
+	Long: `Migrates every manager to the given schema version. Applied migrations newer than the version are
+reverted, newest first, and pending migrations up to and including the version are applied. Version 0 reverts
+all migrations.
+
+Versions are numbered per manager, use --manager to migrate selected managers only.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 2 {
+			fmt.Println(cmd.UsageString())
+			return
+		}
+
+		version, err := strconv.Atoi(args[0])
+		if err != nil || version < 0 {
+			log.Fatalf("Expected a schema version but got: %s", args[0])
+		}
+
+		db, err := Connect(args[1])
+		if err != nil {
+			log.Fatalf("Could not connect to database because: %s", err)
+		}
+
+		m := &Migrator{DB: db}
+		sets := selectedMigrationSets(cmd, db)
+		if err := m.WithLock(func() error {
+			for i := len(sets) - 1; i >= 0; i-- {
+				applied, reverted, err := m.To(sets[i], version)
+				printMigrations("Reverted", sets[i], reverted)
+				printMigrations("Applied", sets[i], applied)
+				if err != nil {
+					return errors.Wrapf(err, "Could not migrate %s schema", sets[i].Manager)
+				}
+			}
+			return nil
+		}); err != nil {
+			log.Fatalf("Could not migrate to version %d because: %s", version, err)
+		}
+	},
+}
+
+func init() {
+	migrateCmd.AddCommand(migrateToCmd)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// migrateUpCmd represents the migrate up command
+var migrateUpCmd = &cobra.Command{
+	Use:   "up <oracle-url>",
+	Short: "Applies all pending migrations",
+	Run:   runMigrateUp,
+}
+
+func runMigrateUp(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		fmt.Println(cmd.UsageString())
+		return
+	}
+
+	db, err := Connect(args[0])
+	if err != nil {
+		log.Fatalf("Could not connect to database because: %s", err)
+	}
+
+	m := &Migrator{DB: db}
+	var failed bool
+	if err := m.WithLock(func() error {
+		for _, set := range selectedMigrationSets(cmd, db) {
+			n, err := m.Up(set)
+			fmt.Printf("Applied %d migrations to %s schema (%s)\n", n, set.Manager, set.Table)
+			if err != nil {
+				log.Printf("Could not migrate %s schema because: %s", set.Manager, err)
+				failed = true
+			}
+		}
+		return nil
+	}); err != nil {
+		log.Fatalf("Could not migrate schemas because: %s", err)
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func init() {
+	migrateCmd.AddCommand(migrateUpCmd)
+}
@@ -2,8 +2,10 @@ package main
 
 import (
 	"fmt"
+	"log"
 	"os"
 	"net/http"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -13,8 +15,15 @@ var cfgFile string
 
 // RootCmd represents the base command when called without any subcommands
 var RootCmd = &cobra.Command{
-	Use: "hydra-oracle-plugin",
+	Use: "hydra-oracle-plugin [oracle-url]",
+	Long: `Serves runtime statistics on :4040/debug/vars. If a DSN is given, the statistics include the connection pool
+of that database under oracle_pools.`,
 	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 1 {
+			if _, err := Connect(args[0]); err != nil {
+				log.Fatalf("Could not connect to database because: %s", err)
+			}
+		}
 		http.ListenAndServe(":4040", nil)
 	},
 }
@@ -36,6 +45,22 @@ func init() {
 	// will be global for your application.
 
 	RootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.hydra-oracle-plugin.yaml)")
+	RootCmd.PersistentFlags().String("table-prefix", "hyd", "Prefix of all table names which are not set explicitly")
+	RootCmd.PersistentFlags().String("table-client", "", "Name of the client table (default <prefix>_clt)")
+	RootCmd.PersistentFlags().String("table-group", "", "Base name of the group tables (default <prefix>_grp)")
+	RootCmd.PersistentFlags().String("table-jwk", "", "Name of the JSON Web Key table (default <prefix>_jwk)")
+	RootCmd.PersistentFlags().String("table-oauth2", "", "Base name of the OAuth2 token tables (default <prefix>_oa2)")
+	RootCmd.PersistentFlags().String("table-policy", "", "Base name of the policy tables (default <prefix>_pol)")
+	RootCmd.PersistentFlags().String("table-migration", "", "Name of the migration bookkeeping table (default <prefix>_mig)")
+	viper.BindPFlag(configTablePrefix, RootCmd.PersistentFlags().Lookup("table-prefix"))
+	viper.BindPFlag(configTableClient, RootCmd.PersistentFlags().Lookup("table-client"))
+	viper.BindPFlag(configTableGroup, RootCmd.PersistentFlags().Lookup("table-group"))
+	viper.BindPFlag(configTableJWK, RootCmd.PersistentFlags().Lookup("table-jwk"))
+	viper.BindPFlag(configTableOAuth2, RootCmd.PersistentFlags().Lookup("table-oauth2"))
+	viper.BindPFlag(configTablePolicy, RootCmd.PersistentFlags().Lookup("table-policy"))
+	viper.BindPFlag(configTableMigration, RootCmd.PersistentFlags().Lookup("table-migration"))
+	RootCmd.PersistentFlags().Duration("connect-retry-timeout", time.Minute, "Time to retry connecting to the database, 0 disables retries")
+	viper.BindPFlag(configConnectRetryTimeout, RootCmd.PersistentFlags().Lookup("connect-retry-timeout"))
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
 	RootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
@@ -53,6 +78,6 @@ func initConfig() {
 
 	// If a config file is found, read it in.
 	if err := viper.ReadInConfig(); err == nil {
-		fmt.Println("Using config file:", viper.ConfigFileUsed())
+		fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
 	}
 }
@@ -0,0 +1,17 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// schemaCmd represents the schema command
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Inspects the Oracle schema of all managers",
+}
+
+func init() {
+	RootCmd.AddCommand(schemaCmd)
+
+	schemaCmd.PersistentFlags().StringSlice("manager", []string{}, "Restricts the command to the given managers (client, group, jwk, oauth2, policy)")
+}
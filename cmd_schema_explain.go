@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// schemaExplainCmd represents the schema explain command
+var schemaExplainCmd = &cobra.Command{
+	Use:   "explain <oracle-url>",
+	Short: "Shows the execution plans of the lookups the managers run on hot paths",
+	Long: `Shows the execution plans Oracle chooses for the lookups of the managers, such as revoking all tokens of a
+request or finding the policies of a subject. A plan accessing a table with TABLE ACCESS FULL instead of an index
+usually means that an index is missing or that the optimizer statistics of the table are stale. Lookups which are
+supported by an index of the plugin name the index they are expected to scan.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			fmt.Println(cmd.UsageString())
+			return
+		}
+
+		db, err := Connect(args[0])
+		if err != nil {
+			log.Fatalf("Could not connect to database because: %s", err)
+		}
+
+		for _, set := range selectedMigrationSets(cmd, db) {
+			for _, lookup := range set.Lookups {
+				plan, err := Explain(db, lookup.Query)
+				if err != nil {
+					log.Fatalf("Could not explain %s of %s because: %s", lookup.Name, set.Manager, err)
+				}
+				name := lookup.Name
+				if lookup.Index != "" {
+					name += fmt.Sprintf(" (expects %s)", strings.ToUpper(lookup.Index))
+				}
+				fmt.Printf("%s: %s\n%s\n\n", set.Manager, name, strings.Join(plan, "\n"))
+			}
+		}
+	},
+}
+
+func init() {
+	schemaCmd.AddCommand(schemaExplainCmd)
+}
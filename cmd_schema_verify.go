@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// schemaVerifyCmd represents the schema verify command
+var schemaVerifyCmd = &cobra.Command{
+	Use:   "verify <oracle-url>",
+	Short: "Compares the live schema with the schema expected by the managers",
+	Long: `Compares the columns, data types, lengths, nullability, primary keys, foreign keys and indexes of every
+table with the definitions expected by the managers. Differences are printed and the command exits with a non-zero
+status if any were found.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			fmt.Println(cmd.UsageString())
+			return
+		}
+
+		db, err := Connect(args[0])
+		if err != nil {
+			log.Fatalf("Could not connect to database because: %s", err)
+		}
+
+		var diff []SchemaDifference
+		for _, set := range selectedMigrationSets(cmd, db) {
+			d, err := VerifySchema(db, set.Tables)
+			if err != nil {
+				log.Fatalf("Could not verify %s schema because: %s", set.Manager, err)
+			}
+			diff = append(diff, d...)
+		}
+
+		if format, _ := cmd.Flags().GetString("format"); format == "json" {
+			if diff == nil {
+				diff = []SchemaDifference{}
+			}
+			if err := json.NewEncoder(os.Stdout).Encode(diff); err != nil {
+				log.Fatalf("Could not encode schema differences because: %s", err)
+			}
+		} else {
+			w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+			fmt.Fprintln(w, "TABLE\tOBJECT\tNAME\tEXPECTED\tACTUAL")
+			for _, d := range diff {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", d.Table, d.Object, d.Name, d.Expected, d.Actual)
+			}
+			w.Flush()
+		}
+
+		if len(diff) > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	schemaCmd.AddCommand(schemaVerifyCmd)
+
+	schemaVerifyCmd.Flags().String("format", "text", "Output format of the differences, one of text, json")
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// tokensCmd represents the tokens command
+var tokensCmd = &cobra.Command{
+	Use:   "tokens",
+	Short: "Maintains the OAuth2 token tables",
+}
+
+func init() {
+	RootCmd.AddCommand(tokensCmd)
+
+	tokensCmd.PersistentFlags().Duration("access-retention", 0, "Retention of access tokens, 0 keeps them forever")
+	tokensCmd.PersistentFlags().Duration("refresh-retention", 0, "Retention of refresh tokens, 0 keeps them forever")
+	tokensCmd.PersistentFlags().Duration("code-retention", 0, "Retention of authorize codes, 0 keeps them forever")
+	tokensCmd.PersistentFlags().Duration("openid-retention", 0, "Retention of OpenID Connect sessions, 0 keeps them forever")
+	viper.BindPFlag(configOAuth2RetentionAccess, tokensCmd.PersistentFlags().Lookup("access-retention"))
+	viper.BindPFlag(configOAuth2RetentionRefresh, tokensCmd.PersistentFlags().Lookup("refresh-retention"))
+	viper.BindPFlag(configOAuth2RetentionCode, tokensCmd.PersistentFlags().Lookup("code-retention"))
+	viper.BindPFlag(configOAuth2RetentionOpenID, tokensCmd.PersistentFlags().Lookup("openid-retention"))
+}
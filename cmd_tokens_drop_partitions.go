@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// tokensDropPartitionsCmd represents the tokens drop-partitions command
+var tokensDropPartitionsCmd = &cobra.Command{
+	Use:   "drop-partitions <oracle-url>",
+	Short: "Drops partitions of the token tables which only contain expired sessions",
+	Long: `Drops partitions of the interval partitioned token tables which only contain sessions requested before
+the retention of the table. Dropping a partition is a cheap metadata operation compared to deleting its rows.
+
+Tables are only partitioned if OAUTH2_PARTITION_INTERVAL was set when they were created or if they were partitioned
+with tokens partition. Tables without a retention are skipped.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			fmt.Println(cmd.UsageString())
+			return
+		}
+
+		db, err := Connect(args[0])
+		if err != nil {
+			log.Fatalf("Could not connect to database because: %s", err)
+		}
+
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		store := &FositeStore{DB: db, Table: tableName(configTableOAuth2)}
+		for _, table := range fositeTableKinds {
+			retention := viper.GetDuration(fositeRetentions[table])
+			if retention <= 0 {
+				fmt.Printf("Skipped %s_%s, no retention configured\n", store.GetTable(), table)
+				continue
+			}
+
+			partitions, err := store.ExpiredPartitions(table, time.Now().UTC().Add(-retention))
+			if err != nil {
+				log.Fatalf("Could not find expired partitions of %s_%s because: %s", store.GetTable(), table, err)
+			}
+
+			for _, partition := range partitions {
+				if dryRun {
+					fmt.Printf("Would drop partition %s of %s_%s\n", partition, store.GetTable(), table)
+					continue
+				}
+
+				if err := store.DropPartition(table, partition); err != nil {
+					log.Fatalf("Could not drop expired partitions because: %s", err)
+				}
+				fmt.Printf("Dropped partition %s of %s_%s\n", partition, store.GetTable(), table)
+			}
+		}
+	},
+}
+
+func init() {
+	tokensCmd.AddCommand(tokensDropPartitionsCmd)
+
+	tokensDropPartitionsCmd.Flags().Bool("dry-run", false, "Lists the expired partitions without dropping them")
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// tokensGCCmd represents the tokens gc command
+var tokensGCCmd = &cobra.Command{
+	Use:   "gc <oracle-url>",
+	Short: "Deletes sessions of the token tables which are older than their retention",
+	Long: `Deletes sessions requested before the retention of their table in batches, each of which is committed on
+its own. Tables without a retention are skipped.
+
+Without --interval, expired sessions are deleted once. With --interval, they are deleted repeatedly until the process
+receives SIGINT or SIGTERM. The garbage collector can also run within ORY Hydra by setting OAUTH2_GC_INTERVAL.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			fmt.Println(cmd.UsageString())
+			return
+		}
+
+		db, err := Connect(args[0])
+		if err != nil {
+			log.Fatalf("Could not connect to database because: %s", err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+		go func() {
+			<-signals
+			cancel()
+		}()
+
+		gc := newGarbageCollector(&FositeStore{DB: db, Table: tableName(configTableOAuth2)}, logrus.StandardLogger())
+		if gc.Interval > 0 {
+			gc.Run(ctx)
+			return
+		}
+
+		counts, err := gc.Collect(ctx)
+		for _, table := range fositeTableKinds {
+			if n, ok := counts[gc.Store.GetTable()+"_"+table]; ok {
+				fmt.Printf("Deleted %d expired sessions of %s_%s\n", n, gc.Store.GetTable(), table)
+			}
+		}
+		if err != nil && ctx.Err() == nil {
+			log.Fatalf("Could not delete expired sessions because: %s", err)
+		}
+	},
+}
+
+func init() {
+	tokensCmd.AddCommand(tokensGCCmd)
+
+	tokensGCCmd.Flags().Duration("interval", 0, "Deletes expired sessions repeatedly in this interval instead of once")
+	tokensGCCmd.Flags().Int("batch-size", 1000, "Maximum number of sessions deleted and committed at once")
+	tokensGCCmd.Flags().Duration("batch-interval", 0, "Pause between batches, limiting the load on the database")
+	viper.BindPFlag(configOAuth2GCInterval, tokensGCCmd.Flags().Lookup("interval"))
+	viper.BindPFlag(configOAuth2GCBatchSize, tokensGCCmd.Flags().Lookup("batch-size"))
+	viper.BindPFlag(configOAuth2GCBatchInterval, tokensGCCmd.Flags().Lookup("batch-interval"))
+}
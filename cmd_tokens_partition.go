@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/spf13/cobra"
+)
+
+// tokensPartitionCmd represents the tokens partition command
+var tokensPartitionCmd = &cobra.Command{
+	Use:   "partition <oracle-url>",
+	Short: "Partitions token tables which were created without partitioning",
+	Long: `Interval partitions the token tables on REQUESTED_AT as configured by OAUTH2_PARTITION_INTERVAL. Only tables
+created while OAUTH2_PARTITION_INTERVAL was set are partitioned by migrations, this command partitions the tables of
+existing installations. Tables which are partitioned already are skipped.
+
+The tables are partitioned online, which requires Oracle 12.2 or later and the Oracle Partitioning option. On earlier
+versions, print the statements with --dry-run and partition the tables with DBMS_REDEFINITION instead.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			fmt.Println(cmd.UsageString())
+			return
+		}
+
+		db, err := Connect(args[0])
+		if err != nil {
+			log.Fatalf("Could not connect to database because: %s", err)
+		}
+
+		store := &FositeStore{DB: db, Table: tableName(configTableOAuth2)}
+		statements, err := store.PartitionStatements()
+		if err != nil {
+			log.Fatalf("Could not partition the token tables because: %s", err)
+		}
+
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		for _, statement := range statements {
+			if dryRun {
+				fmt.Printf("%s;\n", statement)
+				continue
+			}
+
+			if _, err := db.Exec(statement); err != nil {
+				log.Fatalf("Could not partition the token tables because: %s: %s", err, statement)
+			}
+			fmt.Println(statement)
+		}
+	},
+}
+
+func init() {
+	tokensCmd.AddCommand(tokensPartitionCmd)
+
+	tokensPartitionCmd.Flags().Bool("dry-run", false, "Prints the statements without executing them")
+}
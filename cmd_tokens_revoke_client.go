@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/spf13/cobra"
+)
+
+// tokensRevokeClientCmd represents the tokens revoke-client command
+var tokensRevokeClientCmd = &cobra.Command{
+	Use:   "revoke-client <oracle-url> <client-id>",
+	Short: "Revokes all tokens of a client",
+	Long: `Deletes all access and refresh tokens, authorize codes and OpenID Connect sessions of a client in one
+transaction, e.g. after its secret was leaked. The client itself is not deleted.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 2 {
+			fmt.Println(cmd.UsageString())
+			return
+		}
+
+		db, err := Connect(args[0])
+		if err != nil {
+			log.Fatalf("Could not connect to database because: %s", err)
+		}
+
+		store := &FositeStore{DB: db, Table: tableName(configTableOAuth2)}
+		counts, err := store.RevokeClientTokens(context.Background(), args[1])
+		if err != nil {
+			log.Fatalf("Could not revoke tokens because: %s", err)
+		}
+		printRevokedSessions(store, counts)
+	},
+}
+
+// printRevokedSessions prints the number of revoked sessions of each token table.
+func printRevokedSessions(store *FositeStore, counts map[string]int64) {
+	for _, table := range fositeTableKinds {
+		fmt.Printf("Revoked %d sessions of %s_%s\n", counts[store.GetTable()+"_"+table], store.GetTable(), table)
+	}
+}
+
+func init() {
+	tokensCmd.AddCommand(tokensRevokeClientCmd)
+}
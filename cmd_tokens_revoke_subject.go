@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/spf13/cobra"
+)
+
+// tokensRevokeSubjectCmd represents the tokens revoke-subject command
+var tokensRevokeSubjectCmd = &cobra.Command{
+	Use:   "revoke-subject <oracle-url> <subject>",
+	Short: "Revokes all tokens of a subject",
+	Long: `Deletes all access and refresh tokens, authorize codes and OpenID Connect sessions of a subject in one
+transaction, e.g. after the user's account was compromised or deleted.
+
+Sessions are found by the hash of their subject, which migration 7 of the oauth2 manager backfills for existing
+sessions unless it was applied from a migrate plan. Sessions without the hash are not revoked and are deleted by
+tokens gc once their retention has passed.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 2 {
+			fmt.Println(cmd.UsageString())
+			return
+		}
+
+		db, err := Connect(args[0])
+		if err != nil {
+			log.Fatalf("Could not connect to database because: %s", err)
+		}
+
+		store := &FositeStore{DB: db, Table: tableName(configTableOAuth2)}
+		counts, err := store.RevokeSubjectTokens(context.Background(), args[1])
+		if err != nil {
+			log.Fatalf("Could not revoke tokens because: %s", err)
+		}
+		printRevokedSessions(store, counts)
+	},
+}
+
+func init() {
+	tokensCmd.AddCommand(tokensRevokeSubjectCmd)
+}
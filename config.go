@@ -0,0 +1,109 @@
+package main
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// Configuration keys follow the environment variable naming of ORY Hydra, so each key can be set in the config
+// file or as an environment variable of the same name.
+const (
+	configMigrateLockTimeout = "MIGRATE_LOCK_TIMEOUT"
+
+	configTablePrefix    = "TABLE_PREFIX"
+	configTableClient    = "TABLE_CLIENT"
+	configTableGroup     = "TABLE_GROUP"
+	configTableJWK       = "TABLE_JWK"
+	configTableOAuth2    = "TABLE_OAUTH2"
+	configTablePolicy    = "TABLE_POLICY"
+	configTableMigration = "TABLE_MIGRATION"
+
+	configTablespace      = "TABLESPACE"
+	configIndexTablespace = "INDEX_TABLESPACE"
+	configLOBTablespace   = "LOB_TABLESPACE"
+	configTablePctFree    = "TABLE_PCTFREE"
+	configTableCompress   = "TABLE_COMPRESS"
+	configLOBType         = "LOB_TYPE"
+	configLOBParameters   = "LOB_PARAMETERS"
+
+	configOAuth2PartitionInterval = "OAUTH2_PARTITION_INTERVAL"
+	configOAuth2RetentionAccess   = "OAUTH2_RETENTION_ACCESS"
+	configOAuth2RetentionRefresh  = "OAUTH2_RETENTION_REFRESH"
+	configOAuth2RetentionCode     = "OAUTH2_RETENTION_CODE"
+	configOAuth2RetentionOpenID   = "OAUTH2_RETENTION_OPENID"
+	configOAuth2GCInterval        = "OAUTH2_GC_INTERVAL"
+	configOAuth2GCBatchSize       = "OAUTH2_GC_BATCH_SIZE"
+	configOAuth2GCBatchInterval   = "OAUTH2_GC_BATCH_INTERVAL"
+
+	configPolicyConditions = "POLICY_CONDITIONS"
+
+	configSessionNLSDateFormat      = "SESSION_NLS_DATE_FORMAT"
+	configSessionNLSTimestampFormat = "SESSION_NLS_TIMESTAMP_FORMAT"
+	configSessionTimeZone           = "SESSION_TIME_ZONE"
+	configSessionModule             = "SESSION_MODULE"
+	configSessionAction             = "SESSION_ACTION"
+	configSessionStatements         = "SESSION_STATEMENTS"
+
+	configPoolMaxOpenConns    = "POOL_MAX_OPEN_CONNS"
+	configPoolMaxIdleConns    = "POOL_MAX_IDLE_CONNS"
+	configPoolConnMaxLifetime = "POOL_CONN_MAX_LIFETIME"
+	configPoolConnMaxIdleTime = "POOL_CONN_MAX_IDLE_TIME"
+
+	configConnectRetryTimeout         = "CONNECT_RETRY_TIMEOUT"
+	configConnectRetryInitialInterval = "CONNECT_RETRY_INITIAL_INTERVAL"
+	configConnectRetryMaxInterval     = "CONNECT_RETRY_MAX_INTERVAL"
+
+	configDriver = "DRIVER"
+
+	configQueryTimeout = "QUERY_TIMEOUT"
+)
+
+// tableSuffixes are appended to the table prefix if no table name is configured.
+var tableSuffixes = map[string]string{
+	configTableClient:    "clt",
+	configTableGroup:     "grp",
+	configTableJWK:       "jwk",
+	configTableOAuth2:    "oa2",
+	configTablePolicy:    "pol",
+	configTableMigration: "mig",
+}
+
+// Managers derive the names of further tables, constraints and indexes by appending up to ten characters to their
+// table name, which must stay within the 30 character identifier limit of Oracle 11g.
+var tableNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_$#]{0,19}$`)
+
+func init() {
+	// The plugin is loaded by ORY Hydra without running a command, so environment variables are read here
+	// rather than in initConfig.
+	viper.AutomaticEnv()
+
+	viper.SetDefault(configMigrateLockTimeout, time.Minute)
+	viper.SetDefault(configTablePrefix, "hyd")
+	viper.SetDefault(configPolicyConditions, policyConditionsBLOB)
+	viper.SetDefault(configSessionModule, "hydra")
+	viper.SetDefault(configConnectRetryTimeout, time.Minute)
+	viper.SetDefault(configConnectRetryInitialInterval, time.Second)
+	viper.SetDefault(configConnectRetryMaxInterval, 15*time.Second)
+	viper.SetDefault(configQueryTimeout, 30*time.Second)
+	viper.SetDefault(configOAuth2GCBatchSize, 1000)
+}
+
+// tableName returns the configured table name of key, or the table prefix joined with the suffix of key.
+func tableName(key string) string {
+	if name := viper.GetString(key); name != "" {
+		return name
+	}
+	return viper.GetString(configTablePrefix) + "_" + tableSuffixes[key]
+}
+
+func validateTableNames() error {
+	for key := range tableSuffixes {
+		if name := tableName(key); !tableNamePattern.MatchString(name) {
+			return errors.Errorf("Table name %s of %s must start with a letter, contain only letters, digits, _, $ and # and be at most 20 characters long", name, key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestTableName(t *testing.T) {
+	defer viper.Set(configTablePrefix, "hyd")
+	defer viper.Set(configTableClient, "")
+
+	if name := tableName(configTableClient); name != "hyd_clt" {
+		t.Fatalf("Expected default table name hyd_clt but got %s", name)
+	}
+
+	viper.Set(configTablePrefix, "dev")
+	if name := tableName(configTableOAuth2); name != "dev_oa2" {
+		t.Fatalf("Expected prefixed table name dev_oa2 but got %s", name)
+	}
+
+	viper.Set(configTableClient, "clients")
+	if name := tableName(configTableClient); name != "clients" {
+		t.Fatalf("Expected configured table name clients but got %s", name)
+	}
+
+	viper.Set(configTablePrefix, "dev; DROP TABLE x")
+	if err := validateTableNames(); err == nil {
+		t.Fatal("Expected invalid table prefix to be rejected")
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+var scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+
+// dbConn is a single connection of the pool. It offers GetContext and SelectContext like the pool does, which the
+// vendored version of sqlx only implements for pools and transactions, and maps columns with the mapper of the pool.
+type dbConn struct {
+	*sql.Conn
+	db *sqlx.DB
+}
+
+// GetContext scans the first row of the query into dest and returns sql.ErrNoRows if there is none.
+func (c *dbConn) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	rows, err := c.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return sql.ErrNoRows
+	}
+	if err := scanRow(rows, dest); err != nil {
+		return err
+	}
+	return rows.Close()
+}
+
+// SelectContext appends all rows of the query to the slice dest points to.
+func (c *dbConn) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	slice := reflect.ValueOf(dest)
+	if slice.Kind() != reflect.Ptr || slice.Elem().Kind() != reflect.Slice {
+		return errors.Errorf("Expected a pointer to a slice but got %T", dest)
+	}
+	slice = slice.Elem()
+
+	element := slice.Type().Elem()
+	pointers := element.Kind() == reflect.Ptr
+	if pointers {
+		element = element.Elem()
+	}
+
+	rows, err := c.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		row := reflect.New(element)
+		if err := scanRow(rows, row.Interface()); err != nil {
+			return err
+		}
+		if pointers {
+			slice.Set(reflect.Append(slice, row))
+		} else {
+			slice.Set(reflect.Append(slice, row.Elem()))
+		}
+	}
+	return rows.Err()
+}
+
+// QueryxContext runs the query on the connection and returns rows mapping columns with the mapper of the pool.
+func (c *dbConn) QueryxContext(ctx context.Context, query string, args ...interface{}) (*sqlx.Rows, error) {
+	rows, err := c.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlx.Rows{Rows: rows, Mapper: c.db.Mapper}, nil
+}
+
+// scanRow scans the current row into a struct by its db tags, or into dest itself if it is not a struct or
+// implements sql.Scanner.
+func scanRow(rows *sqlx.Rows, dest interface{}) error {
+	t := reflect.TypeOf(dest)
+	if t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Struct && !t.Implements(scannerType) {
+		return rows.StructScan(dest)
+	}
+	return rows.Scan(dest)
+}
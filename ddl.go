@@ -0,0 +1,222 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+var (
+	tablespacePattern    = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_$#]{0,29}$`)
+	compressPattern      = regexp.MustCompile(`(?i)^(NOCOMPRESS|COMPRESS( BASIC| FOR OLTP| FOR ALL OPERATIONS)?|ROW STORE COMPRESS( BASIC| ADVANCED)?|COLUMN STORE COMPRESS FOR (QUERY|ARCHIVE)( LOW| HIGH)?)$`)
+	lobTypePattern       = regexp.MustCompile(`(?i)^(SECUREFILE|BASICFILE)$`)
+	lobParametersPattern = regexp.MustCompile(`^[A-Za-z0-9_ ]+$`)
+)
+
+var partitionIntervals = map[string]string{
+	"DAY":   "NUMTODSINTERVAL(1, 'DAY')",
+	"WEEK":  "NUMTODSINTERVAL(7, 'DAY')",
+	"MONTH": "NUMTOYMINTERVAL(1, 'MONTH')",
+}
+
+// The initial range partition of interval partitioned tables can not be dropped. It holds no rows, as all sessions
+// are requested after its upper bound.
+const initialPartition = "p_initial"
+
+// timePartitioning returns the interval partitioning clause on column as configured by key, or an empty string if
+// partitioning is disabled. The result is appended to the storage clause of CREATE TABLE.
+func timePartitioning(key, column string) string {
+	interval, ok := partitionIntervals[strings.ToUpper(viper.GetString(key))]
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf(" PARTITION BY RANGE (%s) INTERVAL (%s) (PARTITION %s VALUES LESS THAN (TIMESTAMP '2000-01-01 00:00:00'))", column, interval, initialPartition)
+}
+
+// repartitionTable returns the statement which interval partitions an existing table on column as configured by
+// key, or an empty string if partitioning is disabled. The table stays available while it is partitioned, which
+// requires Oracle 12.2 or later.
+func repartitionTable(table, key, column string) string {
+	partitioning := timePartitioning(key, column)
+	if partitioning == "" {
+		return ""
+	}
+	return fmt.Sprintf("ALTER TABLE %s MODIFY%s ONLINE UPDATE INDEXES", table, partitioning)
+}
+
+// tableStorage returns the physical attributes, tablespace and compression of tables as configured, followed by
+// the storage of the given LOB columns. The result is appended to the closing parenthesis of CREATE TABLE.
+func tableStorage(lobColumns ...string) string {
+	var clauses []string
+	if viper.IsSet(configTablePctFree) {
+		clauses = append(clauses, fmt.Sprintf("PCTFREE %d", viper.GetInt(configTablePctFree)))
+	}
+	if tablespace := viper.GetString(configTablespace); tablespace != "" {
+		clauses = append(clauses, "TABLESPACE "+tablespace)
+	}
+	if compress := viper.GetString(configTableCompress); compress != "" {
+		clauses = append(clauses, strings.ToUpper(compress))
+	}
+	if len(lobColumns) > 0 {
+		if lob := lobStorage(lobColumns); lob != "" {
+			clauses = append(clauses, lob)
+		}
+	}
+
+	if len(clauses) == 0 {
+		return ""
+	}
+	return " " + strings.Join(clauses, " ")
+}
+
+func lobStorage(columns []string) string {
+	var parameters []string
+	if tablespace := viper.GetString(configLOBTablespace); tablespace != "" {
+		parameters = append(parameters, "TABLESPACE "+tablespace)
+	}
+	if p := viper.GetString(configLOBParameters); p != "" {
+		parameters = append(parameters, strings.ToUpper(p))
+	}
+
+	kind := strings.ToUpper(viper.GetString(configLOBType))
+	if kind == "" && len(parameters) == 0 {
+		return ""
+	}
+
+	clause := fmt.Sprintf("LOB (%s) STORE AS", strings.Join(columns, ", "))
+	if kind != "" {
+		clause += " " + kind
+	}
+	if len(parameters) > 0 {
+		clause += " (" + strings.Join(parameters, " ") + ")"
+	}
+	return clause
+}
+
+// indexStorage returns the tablespace of indexes as configured. The result is appended to CREATE INDEX.
+func indexStorage() string {
+	if tablespace := viper.GetString(configIndexTablespace); tablespace != "" {
+		return " TABLESPACE " + tablespace
+	}
+	return ""
+}
+
+// constraintStorage returns the tablespace of indexes enforcing primary key and unique constraints as configured.
+// The result is appended to the constraint.
+func constraintStorage() string {
+	if tablespace := viper.GetString(configIndexTablespace); tablespace != "" {
+		return " USING INDEX TABLESPACE " + tablespace
+	}
+	return ""
+}
+
+// convertColumn returns a PL/SQL block changing the data type of a column where ALTER TABLE MODIFY can not, e.g.
+// from VARCHAR2 to CLOB. The values are copied to a new column defined by definition, which is renamed once the old
+// column has been dropped. expression computes the new value from the old column, which is referred to by %s.
+// The block does nothing if the column has the given data type already and resumes interrupted conversions.
+func convertColumn(table, column, dataType, definition, expression string) string {
+	fill := fmt.Sprintf("UPDATE %s SET %s = %s", table, column+"_NEW", fmt.Sprintf(expression, column))
+	return convertColumnWith(table, column, dataType, definition, fill)
+}
+
+// convertColumnWith is like convertColumn, but copies the values with the statement fill, which populates the column
+// suffixed with _NEW from the old column. It is used for conversions which can not be expressed by an UPDATE, e.g.
+// because a PL/SQL block is needed to copy LOBs.
+func convertColumnWith(table, column, dataType, definition, fill string) string {
+	added := column + "_NEW"
+	add := fmt.Sprintf("ALTER TABLE %s ADD (%s %s)", table, added, definition)
+	if dataType == "CLOB" || dataType == "BLOB" {
+		if lob := lobStorage([]string{added}); lob != "" {
+			add += " " + lob
+		}
+	}
+
+	return fmt.Sprintf(`DECLARE
+	current_type VARCHAR2(128);
+	added NUMBER;
+BEGIN
+	SELECT MAX(DATA_TYPE) INTO current_type FROM ALL_TAB_COLUMNS
+	WHERE OWNER = %[1]s AND TABLE_NAME = UPPER(%[2]s) AND COLUMN_NAME = '%[3]s';
+	SELECT COUNT(*) INTO added FROM ALL_TAB_COLUMNS
+	WHERE OWNER = %[1]s AND TABLE_NAME = UPPER(%[2]s) AND COLUMN_NAME = '%[4]s';
+
+	IF current_type = '%[5]s' AND added = 0 THEN
+		RETURN;
+	END IF;
+
+	IF added = 0 THEN
+		EXECUTE IMMEDIATE %[6]s;
+	END IF;
+	IF current_type IS NOT NULL THEN
+		EXECUTE IMMEDIATE %[7]s;
+		EXECUTE IMMEDIATE %[8]s;
+	END IF;
+	EXECUTE IMMEDIATE %[9]s;
+END;`,
+		currentSchemaOwner,
+		quoteLiteral(table),
+		column,
+		added,
+		dataType,
+		quoteLiteral(add),
+		quoteLiteral(fill),
+		quoteLiteral(fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", table, column)),
+		quoteLiteral(fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s", table, added, column)),
+	)
+}
+
+// modifyLongColumn returns a PL/SQL block converting a LONG or LONG RAW column to a LOB of dataType in place. The
+// block does nothing if the column has been converted already.
+func modifyLongColumn(table, column, dataType string) string {
+	modify := fmt.Sprintf("ALTER TABLE %s MODIFY (%s %s)", table, column, dataType)
+	if lob := lobStorage([]string{column}); lob != "" {
+		modify += " " + lob
+	}
+
+	return fmt.Sprintf(`DECLARE
+	current_type VARCHAR2(128);
+BEGIN
+	SELECT MAX(DATA_TYPE) INTO current_type FROM ALL_TAB_COLUMNS
+	WHERE OWNER = %s AND TABLE_NAME = UPPER(%s) AND COLUMN_NAME = '%s';
+
+	IF current_type LIKE 'LONG%%' THEN
+		EXECUTE IMMEDIATE %s;
+	END IF;
+END;`, currentSchemaOwner, quoteLiteral(table), column, quoteLiteral(modify))
+}
+
+func validateStorage() error {
+	for _, key := range []string{configTablespace, configIndexTablespace, configLOBTablespace} {
+		if v := viper.GetString(key); v != "" && !tablespacePattern.MatchString(v) {
+			return errors.Errorf("%s is not a valid tablespace name: %s", key, v)
+		}
+	}
+
+	if viper.IsSet(configTablePctFree) {
+		if v := viper.GetInt(configTablePctFree); v < 0 || v > 99 {
+			return errors.Errorf("%s must be between 0 and 99 but is %d", configTablePctFree, v)
+		}
+	}
+
+	if v := viper.GetString(configTableCompress); v != "" && !compressPattern.MatchString(v) {
+		return errors.Errorf("%s is not a valid table compression clause: %s", configTableCompress, v)
+	}
+	if v := viper.GetString(configLOBType); v != "" && !lobTypePattern.MatchString(v) {
+		return errors.Errorf("%s must be one of SECUREFILE, BASICFILE but is %s", configLOBType, v)
+	}
+	if v := viper.GetString(configLOBParameters); v != "" && !lobParametersPattern.MatchString(v) {
+		return errors.Errorf("%s may contain letters, digits, _ and spaces only but is %s", configLOBParameters, v)
+	}
+	if v := viper.GetString(configOAuth2PartitionInterval); v != "" {
+		if _, ok := partitionIntervals[strings.ToUpper(v)]; !ok {
+			return errors.Errorf("%s must be one of DAY, WEEK, MONTH but is %s", configOAuth2PartitionInterval, v)
+		}
+	}
+	if v := strings.ToUpper(viper.GetString(configPolicyConditions)); v != policyConditionsBLOB && v != policyConditionsJSON {
+		return errors.Errorf("%s must be one of %s, %s but is %s", configPolicyConditions, policyConditionsBLOB, policyConditionsJSON, v)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestTableStorage(t *testing.T) {
+	if s := tableStorage("SESSION_DATA"); s != "" {
+		t.Fatalf("Expected no storage clause by default but got %s", s)
+	}
+
+	for _, key := range []string{configTablespace, configIndexTablespace, configLOBTablespace, configTablePctFree, configTableCompress, configLOBType} {
+		defer viper.Set(key, nil)
+	}
+	viper.Set(configTablespace, "hyd_data")
+	viper.Set(configIndexTablespace, "hyd_idx")
+	viper.Set(configLOBTablespace, "hyd_lob")
+	viper.Set(configTablePctFree, 5)
+	viper.Set(configTableCompress, "compress for oltp")
+	viper.Set(configLOBType, "securefile")
+
+	if err := validateStorage(); err != nil {
+		t.Fatalf("Expected storage configuration to be valid but got %s", err)
+	}
+
+	expected := " PCTFREE 5 TABLESPACE hyd_data COMPRESS FOR OLTP LOB (SESSION_DATA, FORM_DATA) STORE AS SECUREFILE (TABLESPACE hyd_lob)"
+	if s := tableStorage("SESSION_DATA", "FORM_DATA"); s != expected {
+		t.Fatalf("Expected storage clause %s but got %s", expected, s)
+	}
+
+	if s := constraintStorage(); s != " USING INDEX TABLESPACE hyd_idx" {
+		t.Fatalf("Unexpected constraint storage clause %s", s)
+	}
+
+	viper.Set(configTableCompress, "COMPRESS; DROP TABLE x")
+	if err := validateStorage(); err == nil {
+		t.Fatal("Expected invalid compression clause to be rejected")
+	}
+}
+
+func TestConvertColumn(t *testing.T) {
+	db := connect(os.Getenv("ORACLE_DSN"))
+	table := randomTableName("cnv")
+	if _, err := db.Exec(fmt.Sprintf("CREATE TABLE %s (ID varchar(255) NOT NULL PRIMARY KEY, DATA VARCHAR2 (4000) NULL)", table)); err != nil {
+		t.Fatalf("Could not create table: %s", err)
+	}
+	defer db.Exec(fmt.Sprintf("DROP TABLE %s", table))
+
+	if _, err := db.Exec(db.Rebind(fmt.Sprintf("INSERT INTO %s (ID, DATA) VALUES (?, ?)", table)), "foo", "bar"); err != nil {
+		t.Fatalf("Could not insert row: %s", err)
+	}
+
+	// Running the conversion a second time must not change the converted column.
+	for i := 0; i < 2; i++ {
+		if _, err := db.Exec(convertColumn(table, "DATA", "CLOB", "CLOB NULL", "TO_CLOB(%s)")); err != nil {
+			t.Fatalf("Could not convert column: %s", err)
+		}
+	}
+
+	var data clob
+	if err := db.Get(&data, db.Rebind(fmt.Sprintf("SELECT DATA FROM %s WHERE ID=?", table)), "foo"); err != nil {
+		t.Fatalf("Could not select converted column: %s", err)
+	} else if data != "bar" {
+		t.Fatalf("Expected converted value bar but got %s", data)
+	}
+
+	diff, err := VerifySchema(db, []TableDefinition{{
+		Name:       table,
+		Columns:    []ColumnDefinition{{"ID", "VARCHAR2", 255, false}, {"DATA", "CLOB", 0, true}},
+		PrimaryKey: []string{"ID"},
+	}})
+	if err != nil {
+		t.Fatalf("Could not verify schema: %s", err)
+	} else if len(diff) > 0 {
+		t.Fatalf("Expected column to be converted to CLOB but got %v", diff)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+
+	"github.com/pkg/errors"
+)
+
+// The data dictionary is queried for the current schema rather than the login user, because the
+// DSN may select a schema other than the one owned by the user.
+const currentSchemaOwner = "SYS_CONTEXT('USERENV', 'CURRENT_SCHEMA')"
+
+const (
+	objectTable      = "table"
+	objectIndex      = "index"
+	objectConstraint = "constraint"
+	objectColumn     = "column"
+	objectPartitions = "partitioned table"
+)
+
+var objectQueries = map[string]string{
+	objectTable:      "SELECT COUNT(*) FROM ALL_TABLES WHERE OWNER = " + currentSchemaOwner + " AND TABLE_NAME = UPPER(?)",
+	objectIndex:      "SELECT COUNT(*) FROM ALL_INDEXES WHERE OWNER = " + currentSchemaOwner + " AND INDEX_NAME = UPPER(?)",
+	objectConstraint: "SELECT COUNT(*) FROM ALL_CONSTRAINTS WHERE OWNER = " + currentSchemaOwner + " AND TABLE_NAME = UPPER(?) AND CONSTRAINT_NAME = UPPER(?)",
+	objectColumn:     "SELECT COUNT(*) FROM ALL_TAB_COLUMNS WHERE OWNER = " + currentSchemaOwner + " AND TABLE_NAME = UPPER(?) AND COLUMN_NAME = UPPER(?)",
+	objectPartitions: "SELECT COUNT(*) FROM ALL_PART_TABLES WHERE OWNER = " + currentSchemaOwner + " AND TABLE_NAME = UPPER(?)",
+}
+
+// ddlGuards recognize statements which create or drop a single schema object. The first sub match is the table,
+// the optional second sub match the constraint or column of that table.
+var ddlGuards = []struct {
+	pattern *regexp.Regexp
+	object  string
+	creates bool
+}{
+	{pattern: regexp.MustCompile(`(?is)^\s*CREATE\s+TABLE\s+(\w+)`), object: objectTable, creates: true},
+	{pattern: regexp.MustCompile(`(?is)^\s*DROP\s+TABLE\s+(\w+)`), object: objectTable, creates: false},
+	{pattern: regexp.MustCompile(`(?is)^\s*CREATE\s+(?:UNIQUE\s+)?INDEX\s+(\w+)`), object: objectIndex, creates: true},
+	{pattern: regexp.MustCompile(`(?is)^\s*DROP\s+INDEX\s+(\w+)`), object: objectIndex, creates: false},
+	{pattern: regexp.MustCompile(`(?is)^\s*ALTER\s+TABLE\s+(\w+)\s+ADD\s+CONSTRAINT\s+(\w+)`), object: objectConstraint, creates: true},
+	{pattern: regexp.MustCompile(`(?is)^\s*ALTER\s+TABLE\s+(\w+)\s+DROP\s+CONSTRAINT\s+(\w+)`), object: objectConstraint, creates: false},
+	{pattern: regexp.MustCompile(`(?is)^\s*ALTER\s+TABLE\s+(\w+)\s+ADD\s*\(\s*(\w+)`), object: objectColumn, creates: true},
+	{pattern: regexp.MustCompile(`(?is)^\s*ALTER\s+TABLE\s+(\w+)\s+DROP\s+COLUMN\s+(\w+)`), object: objectColumn, creates: false},
+}
+
+// sqlRunner executes statements either on the connection pool or on a single connection, such as the one
+// holding the migration lock.
+type sqlRunner interface {
+	Rebind(query string) string
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+func tableExists(db sqlRunner, table string) (bool, error) {
+	return objectExists(db, objectTable, table)
+}
+
+func objectExists(db sqlRunner, object string, names ...string) (bool, error) {
+	args := make([]interface{}, len(names))
+	for i, name := range names {
+		args[i] = name
+	}
+
+	var count int
+	if err := db.QueryRowContext(context.Background(), db.Rebind(objectQueries[object]), args...).Scan(&count); err != nil {
+		return false, errors.WithStack(err)
+	}
+	return count > 0, nil
+}
+
+// statementSatisfied returns true if the statement creates an object which exists already or drops an object
+// which does not exist. Such statements are skipped, so that migrations can complete partially created schemas.
+func statementSatisfied(db sqlRunner, statement string) (bool, error) {
+	for _, guard := range ddlGuards {
+		match := guard.pattern.FindStringSubmatch(statement)
+		if match == nil {
+			continue
+		}
+
+		exists, err := objectExists(db, guard.object, match[1:]...)
+		if err != nil {
+			return false, err
+		}
+		return exists == guard.creates, nil
+	}
+	return false, nil
+}
@@ -0,0 +1,14 @@
+//go:build goora
+// +build goora
+
+package main
+
+import (
+	_ "github.com/sijms/go-ora"
+)
+
+// go-ora is implemented in pure Go and requires neither the Oracle Instant Client nor cgo, but Go 1.15 or later.
+// Build with the goora tag to compile it in.
+func init() {
+	registerDriver(driverGoOra, goOraDriver)
+}
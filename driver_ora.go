@@ -0,0 +1,20 @@
+//go:build !noora
+// +build !noora
+
+package main
+
+import (
+	"fmt"
+
+	_ "gopkg.in/rana/ora.v4"
+)
+
+// ora.v4 requires the Oracle Instant Client and cgo. Build with the noora tag to leave it out.
+func init() {
+	registerDriver(driverOra, &oracleDriver{
+		Name: "ora",
+		DSN: func(d *DSN) (string, error) {
+			return fmt.Sprintf("%s/%s@%s", d.User, d.Password, d.Connect), nil
+		},
+	})
+}
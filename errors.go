@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/ory/hydra/pkg"
+	"github.com/pkg/errors"
+)
+
+// oracleError is a sentinel error the errors of the drivers are translated to.
+type oracleError struct {
+	message string
+	status  int
+}
+
+func (e *oracleError) Error() string {
+	return e.message
+}
+
+// StatusCode returns the HTTP status code ORY Hydra responds with.
+func (e *oracleError) StatusCode() int {
+	return e.status
+}
+
+var (
+	// ErrConflict is returned if a row with the same key exists, e.g. a client with the same ID.
+	ErrConflict = &oracleError{message: "The resource already exists", status: http.StatusConflict}
+
+	// ErrSchemaMissing is returned if a table or column does not exist, usually because migrations are pending.
+	ErrSchemaMissing = &oracleError{message: "The database schema is missing or outdated, run migrate up", status: http.StatusInternalServerError}
+
+	// ErrTransient is returned if the connection to Oracle was lost. The operation may succeed if it is repeated.
+	ErrTransient = &oracleError{message: "The connection to the database was lost", status: http.StatusServiceUnavailable}
+
+	// ErrRefreshTokenInactive is returned for a refresh token which has been rotated already, along with its session.
+	// Rotating it again revokes all tokens of its grant, as it was most likely stolen.
+	ErrRefreshTokenInactive = &oracleError{message: "The refresh token has been rotated already", status: http.StatusBadRequest}
+
+	// ErrAuthorizeCodeInvalidated is returned for an authorize code which has been redeemed already, along with its
+	// session. Redeeming it again revokes all tokens issued for it, see RFC 6749 section 4.1.2.
+	ErrAuthorizeCodeInvalidated = &oracleError{message: "The authorize code has been redeemed already", status: http.StatusBadRequest}
+)
+
+// oraErrors maps ORA error codes to the sentinel errors they are translated to.
+var oraErrors = map[int]error{
+	1:    ErrConflict,      // unique constraint violated
+	904:  ErrSchemaMissing, // invalid identifier
+	942:  ErrSchemaMissing, // table or view does not exist
+	3113: ErrTransient,     // end-of-file on communication channel
+	3114: ErrTransient,     // not connected to ORACLE
+	3135: ErrTransient,     // connection lost contact
+}
+
+var oraCodePattern = regexp.MustCompile(`ORA-(\d{5})`)
+
+// oraCode returns the ORA error code of err. ora reports the code with the error, while go-ora only includes it in
+// the message.
+func oraCode(err error) (int, bool) {
+	if coder, ok := errors.Cause(err).(interface {
+		Code() int
+	}); ok {
+		return coder.Code(), true
+	}
+
+	if match := oraCodePattern.FindStringSubmatch(err.Error()); match != nil {
+		code, err := strconv.Atoi(match[1])
+		return code, err == nil
+	}
+	return 0, false
+}
+
+// classifyError translates an error of the drivers into pkg.ErrNotFound or a sentinel error of this package, so that
+// errors.Cause can tell conflicts, missing schemas and lost connections apart. Managers expecting another not found
+// error, such as fosite.ErrNotFound, check for sql.ErrNoRows before classifying the error.
+func classifyError(err error) error {
+	if err == nil {
+		return nil
+	} else if errors.Cause(err) == sql.ErrNoRows {
+		return errors.Wrap(pkg.ErrNotFound, "")
+	} else if errors.Cause(err) == driver.ErrBadConn {
+		return errors.Wrap(ErrTransient, err.Error())
+	}
+
+	if code, ok := oraCode(err); ok {
+		if sentinel, ok := oraErrors[code]; ok {
+			return errors.Wrap(sentinel, err.Error())
+		}
+	}
+	return errors.WithStack(err)
+}
+
+// isTransient returns true if err was caused by losing the connection to Oracle.
+func isTransient(err error) bool {
+	return err != nil && errors.Cause(classifyError(err)) == ErrTransient
+}
+
+// transientAttempts is the number of times an operation is attempted if the connection to Oracle is lost.
+const transientAttempts = 3
+
+// retryTransient runs an idempotent operation on a connection of the pool and repeats it on another connection if
+// the connection to Oracle was lost. The connection is pinged before it is returned to the pool, so that the driver
+// reports it as broken with driver.ErrBadConn and database/sql closes it instead of handing it out again.
+func retryTransient(ctx context.Context, db *sqlx.DB, operation func(conn *dbConn) error) (err error) {
+	for attempt := 0; attempt < transientAttempts && ctx.Err() == nil; attempt++ {
+		var conn *sql.Conn
+		conn, err = db.DB.Conn(ctx)
+		if err != nil {
+			return err
+		}
+
+		if err = operation(&dbConn{Conn: conn, db: db}); !isTransient(err) {
+			conn.Close()
+			return err
+		}
+
+		conn.PingContext(ctx)
+		conn.Close()
+	}
+	return err
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+
+	"github.com/ory/hydra/pkg"
+	"github.com/pkg/errors"
+)
+
+type codeError int
+
+func (e codeError) Error() string {
+	return "oracle error"
+}
+
+func (e codeError) Code() int {
+	return int(e)
+}
+
+func TestClassifyError(t *testing.T) {
+	for k, c := range []struct {
+		err      error
+		expected error
+	}{
+		{err: errors.WithStack(codeError(1)), expected: ErrConflict},
+		{err: errors.New("ORA-00942: table or view does not exist"), expected: ErrSchemaMissing},
+		{err: errors.New("ORA-03113: end-of-file on communication channel"), expected: ErrTransient},
+		{err: codeError(3114), expected: ErrTransient},
+		{err: driver.ErrBadConn, expected: ErrTransient},
+		{err: sql.ErrNoRows, expected: pkg.ErrNotFound},
+	} {
+		if err := errors.Cause(classifyError(c.err)); err != c.expected {
+			t.Errorf("Case %d: expected %s to be classified as %s but got %s", k, c.err, c.expected, err)
+		}
+	}
+
+	unknown := errors.New("ORA-01017: invalid username/password; logon denied")
+	if err := errors.Cause(classifyError(unknown)); err != unknown {
+		t.Errorf("Expected unknown errors to be kept but got %s", err)
+	}
+
+	if err := classifyError(nil); err != nil {
+		t.Errorf("Expected nil to be kept but got %s", err)
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// Lookup is a query on a hot path of a manager, such as revoking tokens by request. The execution plans of lookups
+// show whether their supporting indexes are used. Index names the index the lookup is expected to scan, if any.
+type Lookup struct {
+	Name  string
+	Query string
+	Index string
+}
+
+const explainStatementID = "hydra_explain"
+
+// Explain returns the execution plan Oracle chooses for the query, formatted by DBMS_XPLAN. Placeholders of the
+// query are bound to empty strings, which are only used to parse the query. Explaining requires a PLAN_TABLE,
+// which is available to all users by default.
+func Explain(db *sqlx.DB, query string) ([]string, error) {
+	ctx := context.Background()
+	conn, err := db.DB.Conn(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer conn.Close()
+
+	// PLAN_TABLE is a temporary table, so the plan is only visible to the session which explained the query.
+	if _, err := conn.ExecContext(ctx, db.Rebind("DELETE FROM PLAN_TABLE WHERE STATEMENT_ID = ?"), explainStatementID); err != nil {
+		return nil, errors.Wrap(err, "Could not clear the plan table")
+	}
+
+	args := make([]interface{}, strings.Count(query, "?"))
+	for i := range args {
+		args[i] = ""
+	}
+	if _, err := conn.ExecContext(ctx, db.Rebind("EXPLAIN PLAN SET STATEMENT_ID = '"+explainStatementID+"' FOR "+query), args...); err != nil {
+		return nil, errors.Wrapf(err, "Could not explain query: %s", query)
+	}
+
+	rows, err := conn.QueryContext(ctx, db.Rebind("SELECT PLAN_TABLE_OUTPUT FROM TABLE(DBMS_XPLAN.DISPLAY('PLAN_TABLE', ?, 'BASIC'))"), explainStatementID)
+	if err != nil {
+		return nil, errors.Wrap(err, "Could not display the execution plan")
+	}
+	defer rows.Close()
+
+	var plan []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		plan = append(plan, line)
+	}
+	return plan, errors.WithStack(rows.Err())
+}
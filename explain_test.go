@@ -0,0 +1,39 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExplainLookups(t *testing.T) {
+	sets := []*MigrationSet{
+		oauth2Manager.MigrationSet(),
+		groupManager.MigrationSet(),
+		policyManager.MigrationSet(),
+	}
+
+	for _, set := range sets {
+		for _, lookup := range set.Lookups {
+			plan, err := Explain(oauth2Manager.DB, lookup.Query)
+			if err != nil {
+				t.Fatalf("Could not explain %s of %s: %s", lookup.Name, set.Manager, err)
+			}
+
+			if len(plan) == 0 {
+				t.Fatalf("Expected plan of %s of %s not to be empty", lookup.Name, set.Manager)
+			} else if lookup.Index == "" {
+				continue
+			}
+
+			var scanned bool
+			for _, line := range plan {
+				if strings.Contains(line, "INDEX RANGE SCAN") && strings.Contains(line, strings.ToUpper(lookup.Index)) {
+					scanned = true
+				}
+			}
+			if !scanned {
+				t.Errorf("Expected plan of %s of %s to range scan %s but got:\n%s", lookup.Name, set.Manager, lookup.Index, strings.Join(plan, "\n"))
+			}
+		}
+	}
+}
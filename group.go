@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/jmoiron/sqlx"
@@ -12,14 +13,55 @@ import (
 var groupSchema = func(table string) []string {
 	return []string{
 		fmt.Sprintf(`CREATE TABLE %s (
-	id      	varchar(255) NOT NULL PRIMARY KEY
-)`, table),
+	id      	varchar(255) NOT NULL PRIMARY KEY%s
+)%s`, table, constraintStorage(), tableStorage()),
 		fmt.Sprintf(`CREATE TABLE %s_m (
 	member		varchar(255) NOT NULL,
 	group_id	varchar(255) NOT NULL,
 	FOREIGN KEY (group_id) REFERENCES %s(id) ON DELETE CASCADE,
-	PRIMARY KEY (member, group_id)
-)`, table, table),
+	PRIMARY KEY (member, group_id)%s
+)%s`, table, table, constraintStorage(), tableStorage()),
+	}
+}
+
+var groupMigrations = func(table string) []*Migration {
+	return []*Migration{
+		{
+			Version: 1,
+			Up:      groupSchema(table),
+			Down: []string{
+				fmt.Sprintf("DROP TABLE %s_m", table),
+				fmt.Sprintf("DROP TABLE %s", table),
+			},
+		},
+		{
+			// Supports fetching the members of a group, which is not the leading column of the primary key.
+			Version: 2,
+			Up:      []string{fmt.Sprintf("CREATE INDEX %[1]s_m_gid_idx ON %[1]s_m (group_id)%[2]s", table, indexStorage())},
+			Down:    []string{fmt.Sprintf("DROP INDEX %s_m_gid_idx", table)},
+		},
+	}
+}
+
+var groupTables = func(table string) []TableDefinition {
+	return []TableDefinition{
+		{
+			Name: table,
+			Columns: []ColumnDefinition{
+				{"id", "VARCHAR2", 255, false},
+			},
+			PrimaryKey: []string{"id"},
+		},
+		{
+			Name: table + "_m",
+			Columns: []ColumnDefinition{
+				{"member", "VARCHAR2", 255, false},
+				{"group_id", "VARCHAR2", 255, false},
+			},
+			PrimaryKey:  []string{"member", "group_id"},
+			ForeignKeys: []ForeignKeyDefinition{{[]string{"group_id"}, table}},
+			Indexes:     [][]string{{"group_id"}},
+		},
 	}
 }
 
@@ -30,34 +72,38 @@ type GroupManager struct {
 
 func (m *GroupManager) GetTable() string {
 	if m.Table == "" {
-		return "hydgr"
+		return tableName(configTableGroup)
 	}
 	return m.Table
 }
 
-func (m *GroupManager) CreateSchemas() (int, error) {
-	tx, err := m.DB.Begin()
-	if err != nil {
-		return 0, errors.WithStack(err)
+func (m *GroupManager) MigrationSet() *MigrationSet {
+	return &MigrationSet{
+		Manager:    "group",
+		Table:      m.GetTable(),
+		Migrations: groupMigrations(m.GetTable()),
+		Tables:     groupTables(m.GetTable()),
+		Lookups: []Lookup{
+			{Name: "get group members", Query: groupMembersQuery(m.GetTable()), Index: m.GetTable() + "_m_gid_idx"},
+			{Name: "find group names", Query: groupNamesQuery(m.GetTable())},
+		},
 	}
+}
 
-	for _, schema := range groupSchema(m.GetTable()) {
-		if _, err := tx.Exec(schema); err != nil {
-			if err := tx.Rollback(); err != nil {
-				return 0, errors.WithStack(err)
-			}
-			return 0, errors.Wrapf(err, "Could not migrate group sql schema: %s", schema)
-		}
-	}
+var groupMembersQuery = func(table string) string {
+	return fmt.Sprintf("SELECT member from %s_m WHERE group_id = ?", table)
+}
 
-	if err := tx.Commit(); err != nil {
-		if err := tx.Rollback(); err != nil {
-			return 0, errors.WithStack(err)
-		}
-		return 0, errors.WithStack(err)
-	}
+var groupNamesQuery = func(table string) string {
+	return fmt.Sprintf("SELECT group_id from %s_m WHERE member = ? GROUP BY group_id", table)
+}
 
-	return 1, nil
+func (m *GroupManager) CreateSchemas() (int, error) {
+	n, err := (&Migrator{DB: m.DB}).Up(m.MigrationSet())
+	if err != nil {
+		return n, errors.Wrap(err, "Could not migrate group sql schema")
+	}
+	return n, nil
 }
 
 func (m *GroupManager) CreateGroup(g *group.Group) error {
@@ -65,25 +111,33 @@ func (m *GroupManager) CreateGroup(g *group.Group) error {
 		g.ID = uuid.New()
 	}
 
+	ctx, cancel := queryContext(context.Background())
+	defer cancel()
+
 	query := fmt.Sprintf("INSERT INTO %s (id) VALUES (?)", m.GetTable())
-	if _, err := m.DB.Exec(m.DB.Rebind(query), g.ID); err != nil {
-		return errors.WithStack(err)
+	if _, err := m.DB.ExecContext(ctx, m.DB.Rebind(query), g.ID); err != nil {
+		return classifyError(err)
 	}
 
 	return m.AddGroupMembers(g.ID, g.Members)
 }
 
 func (m *GroupManager) GetGroup(id string) (*group.Group, error) {
+	ctx, cancel := queryContext(context.Background())
+	defer cancel()
+
 	var found string
+	var q []string
 	query := fmt.Sprintf("SELECT id from %s WHERE id = ?", m.GetTable())
-	if err := m.DB.Get(&found, m.DB.Rebind(query), id); err != nil {
-		return nil, errors.WithStack(err)
-	}
+	if err := retryTransient(ctx, m.DB, func(conn *dbConn) error {
+		if err := conn.GetContext(ctx, &found, m.DB.Rebind(query), id); err != nil {
+			return err
+		}
 
-	var q []string
-	query = fmt.Sprintf("SELECT member from %s_m WHERE group_id = ?", m.GetTable())
-	if err := m.DB.Select(&q, m.DB.Rebind(query), found); err != nil {
-		return nil, errors.WithStack(err)
+		q = q[:0]
+		return conn.SelectContext(ctx, &q, m.DB.Rebind(groupMembersQuery(m.GetTable())), found)
+	}); err != nil {
+		return nil, classifyError(err)
 	}
 
 	return &group.Group{
@@ -93,26 +147,32 @@ func (m *GroupManager) GetGroup(id string) (*group.Group, error) {
 }
 
 func (m *GroupManager) DeleteGroup(id string) error {
+	ctx, cancel := queryContext(context.Background())
+	defer cancel()
+
 	query := fmt.Sprintf("DELETE FROM %s WHERE id=?", m.GetTable())
-	if _, err := m.DB.Exec(m.DB.Rebind(query), id); err != nil {
-		return errors.WithStack(err)
+	if _, err := m.DB.ExecContext(ctx, m.DB.Rebind(query), id); err != nil {
+		return classifyError(err)
 	}
 	return nil
 }
 
 func (m *GroupManager) AddGroupMembers(group string, subjects []string) error {
-	tx, err := m.DB.Beginx()
+	ctx, cancel := queryContext(context.Background())
+	defer cancel()
+
+	tx, err := m.DB.BeginTxx(ctx, nil)
 	if err != nil {
-		return errors.Wrap(err, "Could not begin transaction")
+		return errors.Wrap(classifyError(err), "Could not begin transaction")
 	}
 
 	query := fmt.Sprintf("INSERT INTO %s_m (group_id, member) VALUES (?, ?)", m.GetTable())
 	for _, subject := range subjects {
-		if _, err := tx.Exec(m.DB.Rebind(query), group, subject); err != nil {
+		if _, err := tx.ExecContext(ctx, m.DB.Rebind(query), group, subject); err != nil {
 			if err := tx.Rollback(); err != nil {
 				return errors.WithStack(err)
 			}
-			return errors.WithStack(err)
+			return classifyError(err)
 		}
 	}
 
@@ -120,24 +180,27 @@ func (m *GroupManager) AddGroupMembers(group string, subjects []string) error {
 		if err := tx.Rollback(); err != nil {
 			return errors.WithStack(err)
 		}
-		return errors.Wrap(err, "Could not commit transaction")
+		return errors.Wrap(classifyError(err), "Could not commit transaction")
 	}
 	return nil
 }
 
 func (m *GroupManager) RemoveGroupMembers(group string, subjects []string) error {
-	tx, err := m.DB.Beginx()
+	ctx, cancel := queryContext(context.Background())
+	defer cancel()
+
+	tx, err := m.DB.BeginTxx(ctx, nil)
 	if err != nil {
-		return errors.Wrap(err, "Could not begin transaction")
+		return errors.Wrap(classifyError(err), "Could not begin transaction")
 	}
 
 	query := fmt.Sprintf("DELETE FROM %s_m WHERE member=? AND group_id=?", m.GetTable())
 	for _, subject := range subjects {
-		if _, err := m.DB.Exec(m.DB.Rebind(query), subject, group); err != nil {
+		if _, err := tx.ExecContext(ctx, m.DB.Rebind(query), subject, group); err != nil {
 			if err := tx.Rollback(); err != nil {
 				return errors.WithStack(err)
 			}
-			return errors.WithStack(err)
+			return classifyError(err)
 		}
 	}
 
@@ -145,17 +208,21 @@ func (m *GroupManager) RemoveGroupMembers(group string, subjects []string) error
 		if err := tx.Rollback(); err != nil {
 			return errors.WithStack(err)
 		}
-		return errors.Wrap(err, "Could not commit transaction")
+		return errors.Wrap(classifyError(err), "Could not commit transaction")
 	}
 	return nil
 }
 
 func (m *GroupManager) FindGroupNames(subject string) ([]string, error) {
-	var q []string
+	ctx, cancel := queryContext(context.Background())
+	defer cancel()
 
-	query := fmt.Sprintf("SELECT group_id from %s_m WHERE member = ? GROUP BY group_id", m.GetTable())
-	if err := m.DB.Select(&q, m.DB.Rebind(query), subject); err != nil {
-		return nil, errors.WithStack(err)
+	var q []string
+	if err := retryTransient(ctx, m.DB, func(conn *dbConn) error {
+		q = q[:0]
+		return conn.SelectContext(ctx, &q, m.DB.Rebind(groupNamesQuery(m.GetTable())), subject)
+	}); err != nil {
+		return nil, classifyError(err)
 	}
 
 	return q, nil
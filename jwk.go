@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 
@@ -25,8 +26,33 @@ var jwkSchema = func(table string) string {
 	KID 	NVARCHAR2(255) NOT NULL,
 	VERSION INTEGER NOT NULL,
 	KEYDATA VARCHAR2 (4000) NOT NULL,
-	CONSTRAINT %s_pk_idx PRIMARY KEY (SID, KID)
-)`, table, table)
+	CONSTRAINT %s_pk_idx PRIMARY KEY (SID, KID)%s
+)%s`, table, table, constraintStorage(), tableStorage())
+}
+
+var jwkMigrations = func(table string) []*Migration {
+	return []*Migration{
+		{
+			Version: 1,
+			Up:      []string{jwkSchema(table)},
+			Down:    []string{fmt.Sprintf("DROP TABLE %s", table)},
+		},
+	}
+}
+
+var jwkTables = func(table string) []TableDefinition {
+	return []TableDefinition{
+		{
+			Name: table,
+			Columns: []ColumnDefinition{
+				{"SID", "NVARCHAR2", 255, false},
+				{"KID", "NVARCHAR2", 255, false},
+				{"VERSION", "NUMBER", 0, false},
+				{"KEYDATA", "VARCHAR2", 4000, false},
+			},
+			PrimaryKey: []string{"SID", "KID"},
+		},
+	}
 }
 
 type jwkSQLData struct {
@@ -38,16 +64,26 @@ type jwkSQLData struct {
 
 func (m *JWKManager) GetTable() string {
 	if m.Table == "" {
-		return "hydjwk"
+		return tableName(configTableJWK)
 	}
 	return m.Table
 }
 
+func (m *JWKManager) MigrationSet() *MigrationSet {
+	return &MigrationSet{
+		Manager:    "jwk",
+		Table:      m.GetTable(),
+		Migrations: jwkMigrations(m.GetTable()),
+		Tables:     jwkTables(m.GetTable()),
+	}
+}
+
 func (m *JWKManager) CreateSchemas() (int, error) {
-	if _, err := m.DB.Exec(jwkSchema(m.GetTable())); err != nil {
-		return 0, errors.Wrap(err, "Could not migrate jwk sql schema")
+	n, err := (&Migrator{DB: m.DB}).Up(m.MigrationSet())
+	if err != nil {
+		return n, errors.Wrap(err, "Could not migrate jwk sql schema")
 	}
-	return 1, nil
+	return n, nil
 }
 
 func (m *JWKManager) AddKey(set string, key *jose.JsonWebKey) error {
@@ -61,22 +97,28 @@ func (m *JWKManager) AddKey(set string, key *jose.JsonWebKey) error {
 		return errors.WithStack(err)
 	}
 
+	ctx, cancel := queryContext(context.Background())
+	defer cancel()
+
 	query := fmt.Sprintf(`INSERT INTO %s (SID, KID, VERSION, KEYDATA) VALUES (:SID, :KID, :VERSION, :KEYDATA)`, m.GetTable())
-	if _, err = m.DB.NamedExec(query, &jwkSQLData{
+	if _, err = m.DB.NamedExecContext(ctx, query, &jwkSQLData{
 		Set:     set,
 		KID:     key.KeyID,
 		Version: 0,
 		Key:     encrypted,
 	}); err != nil {
-		return errors.WithStack(err)
+		return classifyError(err)
 	}
 	return nil
 }
 
 func (m *JWKManager) AddKeySet(set string, keys *jose.JsonWebKeySet) error {
-	tx, err := m.DB.Beginx()
+	ctx, cancel := queryContext(context.Background())
+	defer cancel()
+
+	tx, err := m.DB.BeginTxx(ctx, nil)
 	if err != nil {
-		return errors.WithStack(err)
+		return classifyError(err)
 	}
 
 	for _, key := range keys.Keys {
@@ -97,7 +139,7 @@ func (m *JWKManager) AddKeySet(set string, keys *jose.JsonWebKeySet) error {
 		}
 
 		query := fmt.Sprintf(`INSERT INTO %s (SID, KID, VERSION, KEYDATA) VALUES (:SID, :KID, :VERSION, :KEYDATA)`, m.GetTable())
-		if _, err = tx.NamedExec(query, &jwkSQLData{
+		if _, err = tx.NamedExecContext(ctx, query, &jwkSQLData{
 			Set:     set,
 			KID:     key.KeyID,
 			Version: 0,
@@ -106,7 +148,7 @@ func (m *JWKManager) AddKeySet(set string, keys *jose.JsonWebKeySet) error {
 			if re := tx.Rollback(); re != nil {
 				return errors.Wrap(err, re.Error())
 			}
-			return errors.WithStack(err)
+			return classifyError(err)
 		}
 	}
 
@@ -114,18 +156,23 @@ func (m *JWKManager) AddKeySet(set string, keys *jose.JsonWebKeySet) error {
 		if re := tx.Rollback(); re != nil {
 			return errors.Wrap(err, re.Error())
 		}
-		return errors.WithStack(err)
+		return classifyError(err)
 	}
 	return nil
 }
 
 func (m *JWKManager) GetKey(set, KID string) (*jose.JsonWebKeySet, error) {
+	ctx, cancel := queryContext(context.Background())
+	defer cancel()
+
 	var d jwkSQLData
 	query := fmt.Sprintf("SELECT * FROM %s WHERE SID=? AND KID=?", m.GetTable())
-	if err := m.DB.Get(&d, m.DB.Rebind(query), set, KID); err == sql.ErrNoRows {
+	if err := retryTransient(ctx, m.DB, func(conn *dbConn) error {
+		return conn.GetContext(ctx, &d, m.DB.Rebind(query), set, KID)
+	}); err == sql.ErrNoRows {
 		return nil, errors.Wrap(pkg.ErrNotFound, "")
 	} else if err != nil {
-		return nil, errors.WithStack(err)
+		return nil, classifyError(err)
 	}
 
 	key, err := m.Cipher.Decrypt(d.Key)
@@ -144,12 +191,18 @@ func (m *JWKManager) GetKey(set, KID string) (*jose.JsonWebKeySet, error) {
 }
 
 func (m *JWKManager) GetKeySet(set string) (*jose.JsonWebKeySet, error) {
+	ctx, cancel := queryContext(context.Background())
+	defer cancel()
+
 	var ds []jwkSQLData
 	query := fmt.Sprintf("SELECT * FROM %s WHERE SID=?", m.GetTable())
-	if err := m.DB.Select(&ds, m.DB.Rebind(query), set); err == sql.ErrNoRows {
+	if err := retryTransient(ctx, m.DB, func(conn *dbConn) error {
+		ds = ds[:0]
+		return conn.SelectContext(ctx, &ds, m.DB.Rebind(query), set)
+	}); err == sql.ErrNoRows {
 		return nil, errors.Wrap(pkg.ErrNotFound, "")
 	} else if err != nil {
-		return nil, errors.WithStack(err)
+		return nil, classifyError(err)
 	}
 
 	if len(ds) == 0 {
@@ -174,17 +227,23 @@ func (m *JWKManager) GetKeySet(set string) (*jose.JsonWebKeySet, error) {
 }
 
 func (m *JWKManager) DeleteKey(set, KID string) error {
+	ctx, cancel := queryContext(context.Background())
+	defer cancel()
+
 	query := fmt.Sprintf(`DELETE FROM %s WHERE SID=? AND KID=?`, m.GetTable())
-	if _, err := m.DB.Exec(m.DB.Rebind(query), set, KID); err != nil {
-		return errors.WithStack(err)
+	if _, err := m.DB.ExecContext(ctx, m.DB.Rebind(query), set, KID); err != nil {
+		return classifyError(err)
 	}
 	return nil
 }
 
 func (m *JWKManager) DeleteKeySet(set string) error {
+	ctx, cancel := queryContext(context.Background())
+	defer cancel()
+
 	query := fmt.Sprintf(`DELETE FROM %s WHERE SID=?`, m.GetTable())
-	if _, err := m.DB.Exec(m.DB.Rebind(query), set); err != nil {
-		return errors.WithStack(err)
+	if _, err := m.DB.ExecContext(ctx, m.DB.Rebind(query), set); err != nil {
+		return classifyError(err)
 	}
 	return nil
 }
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"database/sql/driver"
+	"io"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+)
+
+// clob is a string stored in a CLOB column. Depending on the driver and its configuration, LOBs are
+// scanned as strings, byte slices or readers; clob accepts all of them and treats NULL as the empty string.
+type clob string
+
+func (c *clob) Scan(src interface{}) error {
+	b, err := scanLOB(src)
+	if err != nil {
+		return err
+	}
+	*c = clob(b)
+	return nil
+}
+
+func (c clob) Value() (driver.Value, error) {
+	return string(c), nil
+}
+
+// blob is a byte slice stored in a BLOB column. It is scanned like clob and NULL is scanned as nil.
+type blob []byte
+
+func (b *blob) Scan(src interface{}) error {
+	v, err := scanLOB(src)
+	if err != nil {
+		return err
+	}
+	*b = v
+	return nil
+}
+
+func (b blob) Value() (driver.Value, error) {
+	return []byte(b), nil
+}
+
+func scanLOB(src interface{}) ([]byte, error) {
+	switch v := src.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []byte(v), nil
+	case []byte:
+		return append([]byte(nil), v...), nil
+	case io.Reader:
+		b, err := ioutil.ReadAll(v)
+		if err != nil {
+			return nil, errors.Wrap(err, "Could not read LOB")
+		}
+		return b, nil
+	}
+	return nil, errors.Errorf("Could not scan %T into a LOB", src)
+}
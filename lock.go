@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// The lock name is global to the database instance, so it is scoped to the current schema and the bookkeeping
+// table. Migrations of different schemas sharing an instance do not block each other.
+const requestMigrationLock = `DECLARE
+	handle VARCHAR2(128);
+	result INTEGER;
+BEGIN
+	DBMS_LOCK.ALLOCATE_UNIQUE('hydra_migrate.' || SYS_CONTEXT('USERENV', 'CURRENT_SCHEMA') || '.' || UPPER(?), handle);
+	result := DBMS_LOCK.REQUEST(handle, DBMS_LOCK.X_MODE, ?, FALSE);
+	IF result = 1 THEN
+		RAISE_APPLICATION_ERROR(-20001, 'Timed out waiting for the migration lock');
+	ELSIF result NOT IN (0, 4) THEN
+		RAISE_APPLICATION_ERROR(-20002, 'Could not request the migration lock, DBMS_LOCK.REQUEST returned ' || result);
+	END IF;
+END;`
+
+const releaseMigrationLock = `DECLARE
+	handle VARCHAR2(128);
+	result INTEGER;
+BEGIN
+	DBMS_LOCK.ALLOCATE_UNIQUE('hydra_migrate.' || SYS_CONTEXT('USERENV', 'CURRENT_SCHEMA') || '.' || UPPER(?), handle);
+	result := DBMS_LOCK.RELEASE(handle);
+END;`
+
+// lockedConn is the connection holding the migration lock. Migrations run on this connection while the lock is
+// held, so that migrating does not need a second connection from a pool which might be limited to one.
+type lockedConn struct {
+	*sql.Conn
+	db *sqlx.DB
+}
+
+func (c *lockedConn) Rebind(query string) string {
+	return c.db.Rebind(query)
+}
+
+// WithLock runs f while holding the migration lock, which serializes migrations of several processes sharing
+// a schema. The lock is requested with DBMS_LOCK, which requires EXECUTE privileges on DBMS_LOCK. If the lock can
+// not be obtained within the lock timeout, an error is returned and f is not run. Calls of Up, Down and To in f
+// do not request the lock again and execute their statements on the connection holding the lock. Planning migrations does not take the lock.
+func (m *Migrator) WithLock(f func() error) error {
+	if m.conn != nil || m.Plan != nil {
+		return f()
+	}
+
+	ctx := context.Background()
+	conn, err := m.DB.DB.Conn(ctx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer conn.Close()
+
+	timeout := int(m.GetLockTimeout().Seconds())
+	if _, err := conn.ExecContext(ctx, m.DB.Rebind(requestMigrationLock), m.GetTable(), timeout); err != nil {
+		if code, ok := oraCode(err); ok && code == 20001 {
+			return errors.Errorf("Could not obtain the migration lock within %s, another process is migrating the schema", m.GetLockTimeout())
+		}
+		return errors.Wrap(err, "Could not obtain the migration lock")
+	}
+
+	m.conn = &lockedConn{Conn: conn, db: m.DB}
+	ferr := f()
+	m.conn = nil
+
+	if _, err := conn.ExecContext(ctx, m.DB.Rebind(releaseMigrationLock), m.GetTable()); err != nil {
+		if ferr != nil {
+			return errors.Wrap(ferr, err.Error())
+		}
+		return errors.Wrap(err, "Could not release the migration lock")
+	}
+	return ferr
+}
@@ -1,9 +1,9 @@
 package main
 
-import "C"
-
 import (
+	"context"
 	"fmt"
+	"strings"
 
 	"github.com/Sirupsen/logrus"
 	"github.com/jmoiron/sqlx"
@@ -14,7 +14,7 @@ import (
 	"github.com/ory/hydra/warden/group"
 	"github.com/ory/ladon"
 	"github.com/pkg/errors"
-	_ "gopkg.in/rana/ora.v4"
+	"github.com/spf13/viper"
 )
 
 func main() {
@@ -22,20 +22,54 @@ func main() {
 }
 
 func Connect(u string) (*sqlx.DB, error) {
-	host, database := GetDatabase(u)
-	db, err := sqlx.Open("ora", host)
+	// The manager constructors can not return errors, so the configured table names and storage are validated here.
+	if err := validateTableNames(); err != nil {
+		return nil, err
+	} else if err := validateStorage(); err != nil {
+		return nil, err
+	}
+
+	dsn, err := ParseDSN(u)
+	if err != nil {
+		return nil, err
+	}
+
+	driver, err := selectDriver(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	source, err := driver.DSN(dsn)
 	if err != nil {
-		return nil, errors.WithStack(err)
+		return nil, err
 	}
 
-	if err := db.Ping(); err != nil {
-		return nil, errors.WithStack(err)
+	db, err := openSession(driver.Name, source, sessionStatements(dsn))
+	if err != nil {
+		return nil, err
+	}
+	configurePool(db, dsn)
+
+	// Oracle may still be starting when ORY Hydra loads the plugin, so connecting is retried for a while.
+	if err := connectBackoff().Retry("connect to "+dsn.String(), func() error {
+		ctx := context.Background()
+		if dsn.ConnectTimeout != 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, dsn.ConnectTimeout)
+			defer cancel()
+		}
+		return db.PingContext(ctx)
+	}); err != nil {
+		db.Close()
+		return nil, errors.Wrapf(err, "Could not connect to %s", dsn)
 	}
 
-	if _, err := db.Exec(fmt.Sprintf("ALTER SESSION SET CURRENT_SCHEMA = %s", database)); err != nil {
-		return nil, errors.WithStack(err)
+	if err := (&PolicyManager{DB: db, Table: tableName(configTablePolicy)}).verifyConditions(); err != nil {
+		db.Close()
+		return nil, err
 	}
 
+	registerPoolStats(dsn.String(), db)
 	return db, nil
 }
 
@@ -43,14 +77,14 @@ func NewClientManager(db *sqlx.DB, hasher fosite.Hasher) client.Manager {
 	return &ClientManager{
 		DB:     db,
 		Hasher: hasher,
-		Table:  "hyd_clt",
+		Table:  tableName(configTableClient),
 	}
 }
 
 func NewGroupManager(db *sqlx.DB) group.Manager {
 	return &GroupManager{
 		DB:    db,
-		Table: "hyd_grp",
+		Table: tableName(configTableGroup),
 	}
 }
 
@@ -58,57 +92,62 @@ func NewJWKManager(db *sqlx.DB, cipher *jwk.AEAD) jwk.Manager {
 	return &JWKManager{
 		DB:     db,
 		Cipher: cipher,
-		Table:  "hyd_jwk",
+		Table:  tableName(configTableJWK),
 	}
 }
 
 func NewOAuth2Manager(db *sqlx.DB, cm client.Manager, logger logrus.FieldLogger) pkg.FositeStorer {
-	return &FositeStore{
+	store := &FositeStore{
 		Manager: cm,
 		DB:      db,
 		L:       logger,
-		Table:   "hyd_oa2",
+		Table:   tableName(configTableOAuth2),
+	}
+
+	// ORY Hydra does not stop plugins, so the garbage collector is not stopped and runs until the process exits.
+	// Every batch is committed on its own, so exiting while collecting loses at most the current batch.
+	if viper.GetDuration(configOAuth2GCInterval) > 0 {
+		startGarbageCollector(store, logger)
 	}
+	return store
 }
 
 func NewPolicyManager(db *sqlx.DB) ladon.Manager {
 	return &PolicyManager{
 		DB:    db,
-		Table: "hyd_pol",
+		Table: tableName(configTablePolicy),
 	}
 }
 
+// MigrationSets returns the migration sets of all managers in the order in which they are migrated.
+func MigrationSets(db *sqlx.DB) []*MigrationSet {
+	return []*MigrationSet{
+		(&ClientManager{DB: db, Table: tableName(configTableClient)}).MigrationSet(),
+		(&GroupManager{DB: db, Table: tableName(configTableGroup)}).MigrationSet(),
+		(&JWKManager{DB: db, Table: tableName(configTableJWK)}).MigrationSet(),
+		(&FositeStore{DB: db, Table: tableName(configTableOAuth2)}).MigrationSet(),
+		(&PolicyManager{DB: db, Table: tableName(configTablePolicy)}).MigrationSet(),
+	}
+}
+
+// CreateSchemas applies the pending migrations of all managers. A manager failing to migrate does not prevent the
+// remaining managers from being migrated.
 func CreateSchemas(db *sqlx.DB) error {
-	if _, err := (&ClientManager{
-		DB: db,
-		Table:  "hyd_clt",
-	}).CreateSchemas(); err != nil {
-		return errors.WithStack(err)
-	}
-	if _, err := (&GroupManager{
-		DB: db,
-		Table: "hyd_grp",
-	}).CreateSchemas(); err != nil {
-		return errors.WithStack(err)
-	}
-	if _, err := (&JWKManager{
-		DB: db,
-		Table:  "hyd_jwk",
-	}).CreateSchemas(); err != nil {
-		return errors.WithStack(err)
-	}
-	if _, err := (&FositeStore{
-		DB: db,
-		Table:   "hyd_oa2",
-	}).CreateSchemas(); err != nil {
-		return errors.WithStack(err)
-	}
-	if _, err := (&PolicyManager{
-		DB: db,
-		Table: "hyd_pol",
-	}).CreateSchemas(); err != nil {
-		return errors.WithStack(err)
+	m := &Migrator{DB: db}
+	var failed []string
+	if err := m.WithLock(func() error {
+		for _, set := range MigrationSets(db) {
+			if _, err := m.Up(set); err != nil {
+				failed = append(failed, fmt.Sprintf("%s: %s", set.Manager, err))
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
 	}
 
+	if len(failed) > 0 {
+		return errors.Errorf("Could not migrate sql schemas of %s", strings.Join(failed, "; "))
+	}
 	return nil
 }
@@ -0,0 +1,410 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// Migration is a numbered, reversible schema change. Migrations of a MigrationSet are applied in
+// ascending order of their version and each version is applied at most once. Down reverts the
+// statements of Up and is executed in descending order of versions. Backfill, if set, updates existing
+// rows after the statements of Up have been executed, where SQL alone can not compute the new values.
+type Migration struct {
+	Version  int
+	Up       []string
+	Down     []string
+	Backfill func(ctx context.Context, r sqlRunner) error
+}
+
+// MigrationSet groups the migrations of a single manager. The set is identified in the bookkeeping
+// table by the base table name of the manager, so that several instances of a manager (e.g. in tests)
+// may share one schema. Tables describes the schema the manager expects after all migrations have been applied
+// and Lookups the queries these tables are indexed for.
+type MigrationSet struct {
+	Manager    string
+	Table      string
+	Migrations []*Migration
+	Tables     []TableDefinition
+	Lookups    []Lookup
+}
+
+var migrationSchema = func(table string) string {
+	return fmt.Sprintf(`CREATE TABLE %s (
+	MIGRATION_SET	varchar(255) NOT NULL,
+	VERSION 		INTEGER NOT NULL,
+	APPLIED_AT 		TIMESTAMP NOT NULL,
+	CONSTRAINT %[1]s_pk_idx PRIMARY KEY (MIGRATION_SET, VERSION)%[2]s
+)%[3]s`, table, constraintStorage(), tableStorage())
+}
+
+// Migrator applies migration sets and records the applied versions in a bookkeeping table. If Plan is set,
+// the migrator writes the statements it would execute to Plan instead of executing them. LockTimeout is the
+// time to wait for the migration lock held by other processes, see WithLock.
+type Migrator struct {
+	DB          *sqlx.DB
+	Table       string
+	Plan        io.Writer
+	LockTimeout time.Duration
+
+	bookkeeping bool
+	conn        *lockedConn
+}
+
+// MigrationStatus tells if and when a migration has been applied. AppliedAt is nil for pending migrations.
+type MigrationStatus struct {
+	Migration *Migration
+	AppliedAt *time.Time
+}
+
+func (m *Migrator) GetTable() string {
+	if m.Table == "" {
+		return tableName(configTableMigration)
+	}
+	return m.Table
+}
+
+// runner returns the connection holding the migration lock or, if the lock is not held, the connection pool.
+func (m *Migrator) runner() sqlRunner {
+	if m.conn != nil {
+		return m.conn
+	}
+	return m.DB
+}
+
+func (m *Migrator) GetLockTimeout() time.Duration {
+	if m.LockTimeout == 0 {
+		return viper.GetDuration(configMigrateLockTimeout)
+	}
+	return m.LockTimeout
+}
+
+// CreateBookkeeping creates the bookkeeping table unless it exists already.
+func (m *Migrator) CreateBookkeeping() error {
+	if m.bookkeeping {
+		return nil
+	}
+
+	exists, err := tableExists(m.runner(), m.GetTable())
+	if err != nil {
+		return err
+	} else if !exists {
+		m.comment("Create migration bookkeeping table")
+		if err := m.exec(migrationSchema(m.GetTable())); err != nil {
+			return errors.Wrap(err, "Could not create migration bookkeeping table")
+		}
+	}
+
+	m.bookkeeping = true
+	return nil
+}
+
+// Applied returns the versions of the set which have been recorded as applied and when they were applied.
+func (m *Migrator) Applied(set *MigrationSet) (map[int]time.Time, error) {
+	applied := map[int]time.Time{}
+	if exists, err := tableExists(m.runner(), m.GetTable()); err != nil {
+		return nil, err
+	} else if !exists {
+		return applied, nil
+	}
+
+	runner := m.runner()
+	query := fmt.Sprintf("SELECT VERSION, APPLIED_AT FROM %s WHERE MIGRATION_SET=?", m.GetTable())
+	rows, err := runner.QueryContext(context.Background(), runner.Rebind(query), set.Table)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		applied[version] = appliedAt
+	}
+	return applied, errors.WithStack(rows.Err())
+}
+
+// Status returns the status of every migration of the set in ascending order of versions.
+func (m *Migrator) Status(set *MigrationSet) ([]MigrationStatus, error) {
+	if err := set.validate(); err != nil {
+		return nil, err
+	}
+
+	applied, err := m.Applied(set)
+	if err != nil {
+		return nil, err
+	}
+
+	var status []MigrationStatus
+	for _, migration := range set.Migrations {
+		s := MigrationStatus{Migration: migration}
+		if at, ok := applied[migration.Version]; ok {
+			s.AppliedAt = &at
+		}
+		status = append(status, s)
+	}
+	return status, nil
+}
+
+// Pending returns the migrations of the set which have not been applied yet, in the order they
+// need to be applied.
+func (m *Migrator) Pending(set *MigrationSet) ([]*Migration, error) {
+	if err := set.validate(); err != nil {
+		return nil, err
+	}
+
+	applied, err := m.Applied(set)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []*Migration
+	for _, migration := range set.Migrations {
+		if _, ok := applied[migration.Version]; !ok {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// Up applies all pending migrations of the set and returns the number of migrations applied.
+func (m *Migrator) Up(set *MigrationSet) (n int, err error) {
+	err = m.WithLock(func() error {
+		if err := m.CreateBookkeeping(); err != nil {
+			return err
+		}
+
+		pending, err := m.Pending(set)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range pending {
+			if err := m.apply(set, migration); err != nil {
+				return err
+			}
+			n++
+		}
+		return nil
+	})
+	return n, err
+}
+
+// Down reverts the last steps applied migrations of the set, newest first, and returns the reverted
+// migrations in the order they were reverted.
+func (m *Migrator) Down(set *MigrationSet, steps int) (reverted []*Migration, err error) {
+	err = m.WithLock(func() error {
+		if err := m.CreateBookkeeping(); err != nil {
+			return err
+		}
+
+		applied, err := m.appliedMigrations(set)
+		if err != nil {
+			return err
+		}
+
+		if steps < len(applied) {
+			applied = applied[:steps]
+		}
+
+		for _, migration := range applied {
+			if err := m.revert(set, migration); err != nil {
+				return err
+			}
+			reverted = append(reverted, migration)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// To migrates the set to the given version. Applied migrations newer than version are reverted,
+// newest first, after which pending migrations up to and including version are applied. Version 0
+// reverts all migrations of the set.
+func (m *Migrator) To(set *MigrationSet, version int) (applied []*Migration, reverted []*Migration, err error) {
+	err = m.WithLock(func() error {
+		if err := m.CreateBookkeeping(); err != nil {
+			return err
+		}
+
+		current, err := m.appliedMigrations(set)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range current {
+			if migration.Version <= version {
+				continue
+			}
+			if err := m.revert(set, migration); err != nil {
+				return err
+			}
+			reverted = append(reverted, migration)
+		}
+
+		pending, err := m.Pending(set)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range pending {
+			if migration.Version > version {
+				break
+			}
+			if err := m.apply(set, migration); err != nil {
+				return err
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, reverted, err
+}
+
+// appliedMigrations returns the applied migrations of the set, newest first.
+func (m *Migrator) appliedMigrations(set *MigrationSet) ([]*Migration, error) {
+	if err := set.validate(); err != nil {
+		return nil, err
+	}
+
+	applied, err := m.Applied(set)
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []int
+	for v := range applied {
+		versions = append(versions, v)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(versions)))
+
+	var migrations []*Migration
+	for _, v := range versions {
+		migration := set.find(v)
+		if migration == nil {
+			return nil, errors.Errorf("Migration %d of %s has been applied but is unknown to this version of the plugin", v, set.Table)
+		}
+		migrations = append(migrations, migration)
+	}
+	return migrations, nil
+}
+
+func (m *Migrator) apply(set *MigrationSet, migration *Migration) error {
+	m.comment("Apply migration %d of %s schema (%s)", migration.Version, set.Manager, set.Table)
+	for _, statement := range migration.Up {
+		if err := m.exec(statement); err != nil {
+			return errors.Wrapf(err, "Could not apply migration %d of %s: %s", migration.Version, set.Table, statement)
+		}
+	}
+
+	if migration.Backfill != nil && m.Plan != nil {
+		m.comment("Existing rows are backfilled by migrate up only and are left as they are by this plan")
+	} else if migration.Backfill != nil {
+		if err := migration.Backfill(context.Background(), m.runner()); err != nil {
+			return errors.Wrapf(err, "Could not backfill migration %d of %s", migration.Version, set.Table)
+		}
+	}
+
+	var err error
+	if m.Plan != nil {
+		err = m.exec(fmt.Sprintf("INSERT INTO %s (MIGRATION_SET, VERSION, APPLIED_AT) VALUES (%s, %d, SYSTIMESTAMP)", m.GetTable(), quoteLiteral(set.Table), migration.Version))
+	} else {
+		query := fmt.Sprintf("INSERT INTO %s (MIGRATION_SET, VERSION, APPLIED_AT) VALUES (?, ?, SYSTIMESTAMP)", m.GetTable())
+		runner := m.runner()
+		_, err = runner.ExecContext(context.Background(), runner.Rebind(query), set.Table, migration.Version)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "Could not record migration %d of %s", migration.Version, set.Table)
+	}
+	return nil
+}
+
+func (m *Migrator) revert(set *MigrationSet, migration *Migration) error {
+	if len(migration.Down) == 0 {
+		return errors.Errorf("Migration %d of %s can not be reverted", migration.Version, set.Table)
+	}
+
+	m.comment("Revert migration %d of %s schema (%s)", migration.Version, set.Manager, set.Table)
+	for _, statement := range migration.Down {
+		if err := m.exec(statement); err != nil {
+			return errors.Wrapf(err, "Could not revert migration %d of %s: %s", migration.Version, set.Table, statement)
+		}
+	}
+
+	var err error
+	if m.Plan != nil {
+		err = m.exec(fmt.Sprintf("DELETE FROM %s WHERE MIGRATION_SET=%s AND VERSION=%d", m.GetTable(), quoteLiteral(set.Table), migration.Version))
+	} else {
+		query := fmt.Sprintf("DELETE FROM %s WHERE MIGRATION_SET=? AND VERSION=?", m.GetTable())
+		runner := m.runner()
+		_, err = runner.ExecContext(context.Background(), runner.Rebind(query), set.Table, migration.Version)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "Could not remove record of migration %d of %s", migration.Version, set.Table)
+	}
+	return nil
+}
+
+// exec executes the statement or, if the migrator is planning, writes it to the plan in a form which can be
+// run by SQL*Plus. Statements creating objects which exist already or dropping objects which do not exist are
+// skipped.
+func (m *Migrator) exec(statement string) error {
+	if satisfied, err := statementSatisfied(m.runner(), statement); err != nil {
+		return err
+	} else if satisfied {
+		m.comment("Skipped, the schema already reflects: %s", strings.SplitN(strings.TrimSpace(statement), "\n", 2)[0])
+		return nil
+	}
+
+	if m.Plan == nil {
+		_, err := m.runner().ExecContext(context.Background(), statement)
+		return err
+	}
+
+	terminator := ";"
+	if strings.HasSuffix(strings.TrimSpace(statement), "END;") {
+		terminator = "\n/"
+	}
+	_, err := fmt.Fprintf(m.Plan, "%s%s\n\n", statement, terminator)
+	return errors.WithStack(err)
+}
+
+func (m *Migrator) comment(format string, args ...interface{}) {
+	if m.Plan != nil {
+		fmt.Fprintf(m.Plan, "-- "+format+"\n", args...)
+	}
+}
+
+func quoteLiteral(value string) string {
+	return "'" + strings.Replace(value, "'", "''", -1) + "'"
+}
+
+func (s *MigrationSet) find(version int) *Migration {
+	for _, migration := range s.Migrations {
+		if migration.Version == version {
+			return migration
+		}
+	}
+	return nil
+}
+
+func (s *MigrationSet) validate() error {
+	for i, migration := range s.Migrations {
+		if migration.Version < 1 {
+			return errors.Errorf("Migration set %s contains invalid version %d", s.Table, migration.Version)
+		} else if i > 0 && migration.Version <= s.Migrations[i-1].Version {
+			return errors.Errorf("Migrations of set %s are not in ascending order at version %d", s.Table, migration.Version)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMigratorAppliesPendingMigrationsOnce(t *testing.T) {
+	db := connect(os.Getenv("ORACLE_DSN"))
+	table := randomTableName("mig")
+	m := &Migrator{DB: db, Table: table + "_b"}
+	set := &MigrationSet{
+		Manager: "test",
+		Table:   table,
+		Migrations: []*Migration{
+			{Version: 1, Up: []string{fmt.Sprintf("CREATE TABLE %s (ID varchar(255) NOT NULL PRIMARY KEY)", table)}},
+		},
+	}
+
+	n, err := m.Up(set)
+	if err != nil {
+		t.Fatalf("Could not apply migrations: %s", err)
+	} else if n != 1 {
+		t.Fatalf("Expected 1 migration to be applied but got %d", n)
+	}
+
+	set.Migrations = append(set.Migrations, &Migration{
+		Version: 2,
+		Up:      []string{fmt.Sprintf("ALTER TABLE %s ADD (NAME VARCHAR2 (4000) NULL)", table)},
+	})
+
+	n, err = m.Up(set)
+	if err != nil {
+		t.Fatalf("Could not apply migrations: %s", err)
+	} else if n != 1 {
+		t.Fatalf("Expected only the pending migration to be applied but got %d", n)
+	}
+
+	pending, err := m.Pending(set)
+	if err != nil {
+		t.Fatalf("Could not fetch pending migrations: %s", err)
+	} else if len(pending) != 0 {
+		t.Fatalf("Expected no pending migrations but got %d", len(pending))
+	}
+}
+
+func TestMigrationSetRejectsUnorderedVersions(t *testing.T) {
+	set := &MigrationSet{Table: "foo", Migrations: []*Migration{{Version: 2}, {Version: 1}}}
+	if err := set.validate(); err == nil {
+		t.Fatal("Expected unordered migrations to be rejected")
+	}
+}
+
+func TestMigratorDownAndTo(t *testing.T) {
+	db := connect(os.Getenv("ORACLE_DSN"))
+	table := randomTableName("mig")
+	m := &Migrator{DB: db, Table: table + "_b"}
+	set := &MigrationSet{
+		Manager: "test",
+		Table:   table,
+		Migrations: []*Migration{
+			{
+				Version: 1,
+				Up:      []string{fmt.Sprintf("CREATE TABLE %s (ID varchar(255) NOT NULL PRIMARY KEY)", table)},
+				Down:    []string{fmt.Sprintf("DROP TABLE %s", table)},
+			},
+			{
+				Version: 2,
+				Up:      []string{fmt.Sprintf("ALTER TABLE %s ADD (NAME VARCHAR2 (4000) NULL)", table)},
+				Down:    []string{fmt.Sprintf("ALTER TABLE %s DROP COLUMN NAME", table)},
+			},
+		},
+	}
+
+	if _, err := m.Up(set); err != nil {
+		t.Fatalf("Could not apply migrations: %s", err)
+	}
+
+	reverted, err := m.Down(set, 1)
+	if err != nil {
+		t.Fatalf("Could not revert migrations: %s", err)
+	} else if len(reverted) != 1 || reverted[0].Version != 2 {
+		t.Fatalf("Expected migration 2 to be reverted but got %v", reverted)
+	}
+
+	applied, reverted, err := m.To(set, 2)
+	if err != nil {
+		t.Fatalf("Could not migrate to version 2: %s", err)
+	} else if len(applied) != 1 || len(reverted) != 0 {
+		t.Fatalf("Expected one migration to be applied but got %d applied and %d reverted", len(applied), len(reverted))
+	}
+
+	applied, reverted, err = m.To(set, 0)
+	if err != nil {
+		t.Fatalf("Could not migrate to version 0: %s", err)
+	} else if len(applied) != 0 || len(reverted) != 2 {
+		t.Fatalf("Expected two migrations to be reverted but got %d applied and %d reverted", len(applied), len(reverted))
+	}
+}
+
+func TestMigratorPlanDoesNotExecute(t *testing.T) {
+	db := connect(os.Getenv("ORACLE_DSN"))
+	table := randomTableName("mig")
+	var plan bytes.Buffer
+	m := &Migrator{DB: db, Table: table + "_b", Plan: &plan}
+	set := &MigrationSet{
+		Manager: "test",
+		Table:   table,
+		Migrations: []*Migration{
+			{Version: 1, Up: []string{fmt.Sprintf("CREATE TABLE %s (ID varchar(255) NOT NULL PRIMARY KEY)", table)}},
+		},
+	}
+
+	if _, err := m.Up(set); err != nil {
+		t.Fatalf("Could not plan migrations: %s", err)
+	}
+
+	if !strings.Contains(plan.String(), fmt.Sprintf("CREATE TABLE %s (", table)) {
+		t.Fatalf("Expected plan to contain the migration but got: %s", plan.String())
+	}
+
+	if exists, err := tableExists(db, table); err != nil {
+		t.Fatalf("Could not check if table exists: %s", err)
+	} else if exists {
+		t.Fatal("Expected planned migration not to be executed")
+	}
+}
+
+func TestMigratorCompletesPartiallyCreatedSchema(t *testing.T) {
+	db := connect(os.Getenv("ORACLE_DSN"))
+	table := randomTableName("mig")
+	m := &Migrator{DB: db, Table: table + "_b"}
+	set := &MigrationSet{
+		Manager: "test",
+		Table:   table,
+		Migrations: []*Migration{
+			{Version: 1, Up: []string{
+				fmt.Sprintf("CREATE TABLE %s_x (ID varchar(255) NOT NULL PRIMARY KEY)", table),
+				fmt.Sprintf("CREATE TABLE %s_y (ID varchar(255) NOT NULL PRIMARY KEY)", table),
+			}},
+		},
+	}
+
+	if _, err := db.Exec(set.Migrations[0].Up[0]); err != nil {
+		t.Fatalf("Could not create table: %s", err)
+	}
+
+	if _, err := m.Up(set); err != nil {
+		t.Fatalf("Could not complete partially created schema: %s", err)
+	}
+
+	if exists, err := tableExists(db, table+"_y"); err != nil {
+		t.Fatalf("Could not check if table exists: %s", err)
+	} else if !exists {
+		t.Fatal("Expected missing table to be created")
+	}
+}
+
+func TestMigratorLockIsExclusive(t *testing.T) {
+	db := connect(os.Getenv("ORACLE_DSN"))
+	table := randomTableName("mig")
+	holder := &Migrator{DB: db, Table: table + "_b"}
+	waiter := &Migrator{DB: db, Table: table + "_b", LockTimeout: time.Second}
+
+	var waited bool
+	if err := holder.WithLock(func() error {
+		err := waiter.WithLock(func() error {
+			waited = true
+			return nil
+		})
+		if err == nil {
+			t.Error("Expected the migration lock not to be obtained while it is held")
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("Could not obtain migration lock: %s", err)
+	}
+
+	if waited {
+		t.Fatal("Expected function not to run without the migration lock")
+	}
+
+	if err := waiter.WithLock(func() error { return nil }); err != nil {
+		t.Fatalf("Expected the migration lock to be released but got: %s", err)
+	}
+}
+
+func TestMigratorRunsOnLockedConnection(t *testing.T) {
+	db := connect(os.Getenv("ORACLE_DSN"))
+	db.SetMaxOpenConns(1)
+	table := randomTableName("mig")
+	m := &Migrator{DB: db, Table: table + "_b"}
+	set := &MigrationSet{
+		Manager: "test",
+		Table:   table,
+		Migrations: []*Migration{
+			{Version: 1, Up: []string{fmt.Sprintf("CREATE TABLE %s (ID varchar(255) NOT NULL PRIMARY KEY)", table)}},
+		},
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := m.Up(set)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Could not apply migrations with a single connection: %s", err)
+		}
+	case <-time.After(time.Minute):
+		t.Fatal("Expected migrations not to wait for a second connection while holding the migration lock")
+	}
+}
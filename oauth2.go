@@ -2,7 +2,9 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"net/url"
@@ -25,14 +27,14 @@ type FositeStore struct {
 
 func (m *FositeStore) GetTable() string {
 	if m.Table == "" {
-		return "hydoa2"
+		return tableName(configTableOAuth2)
 	}
 	return m.Table
 }
 
 func fositeSqlTemplate(kind, table string) string {
 	return fmt.Sprintf(`CREATE TABLE %s_%s (
-	SIGNATURE      	varchar(255) NOT NULL PRIMARY KEY,
+	SIGNATURE      	varchar(255) NOT NULL PRIMARY KEY%s,
 	REQUEST_ID  	varchar(255) NOT NULL,
 	REQUESTED_AT  	TIMESTAMP NOT NULL,
 	CLIENT_ID  		VARCHAR2 (4000) NULL,
@@ -40,7 +42,268 @@ func fositeSqlTemplate(kind, table string) string {
 	GRANTED_SCOPE 	VARCHAR2 (4000) NULL,
 	FORM_DATA  		VARCHAR2 (4000) NULL,
 	SESSION_DATA  	VARCHAR2 (4000) NULL
-)`, table, kind)
+)%s%s`, table, kind, constraintStorage(), tableStorage(), timePartitioning(configOAuth2PartitionInterval, "REQUESTED_AT"))
+}
+
+var fositeMigrations = func(table string) []*Migration {
+	return []*Migration{
+		{
+			Version: 1,
+			Up: []string{
+				fositeSqlTemplate(sqlTableAccess, table),
+				fositeSqlTemplate(sqlTableRefresh, table),
+				fositeSqlTemplate(sqlTableCode, table),
+				fositeSqlTemplate(sqlTableOpenID, table),
+			},
+			Down: []string{
+				fmt.Sprintf("DROP TABLE %s_%s", table, sqlTableOpenID),
+				fmt.Sprintf("DROP TABLE %s_%s", table, sqlTableCode),
+				fmt.Sprintf("DROP TABLE %s_%s", table, sqlTableRefresh),
+				fmt.Sprintf("DROP TABLE %s_%s", table, sqlTableAccess),
+			},
+		},
+		{
+			// Stores session and form data in CLOBs, as sessions with many claims exceed 4000 characters. Reverting
+			// fails with ORA-22835 rather than truncating sessions which do not fit into VARCHAR2 (4000).
+			Version: 2,
+			Up:      fositeConvertColumns(table, "CLOB", "CLOB NULL", "TO_CLOB(%s)"),
+			Down:    fositeConvertColumns(table, "VARCHAR2", "VARCHAR2 (4000) NULL", "TO_CHAR(%s)"),
+		},
+		{
+			// Supports revoking all tokens of a request.
+			Version: 3,
+			Up: []string{
+				fmt.Sprintf("CREATE INDEX %[1]s_%[2]s_rid_idx ON %[1]s_%[2]s (REQUEST_ID)%[3]s", table, sqlTableAccess, indexStorage()),
+				fmt.Sprintf("CREATE INDEX %[1]s_%[2]s_rid_idx ON %[1]s_%[2]s (REQUEST_ID)%[3]s", table, sqlTableRefresh, indexStorage()),
+				fmt.Sprintf("CREATE INDEX %[1]s_%[2]s_rid_idx ON %[1]s_%[2]s (REQUEST_ID)%[3]s", table, sqlTableCode, indexStorage()),
+				fmt.Sprintf("CREATE INDEX %[1]s_%[2]s_rid_idx ON %[1]s_%[2]s (REQUEST_ID)%[3]s", table, sqlTableOpenID, indexStorage()),
+			},
+			Down: []string{
+				fmt.Sprintf("DROP INDEX %s_%s_rid_idx", table, sqlTableOpenID),
+				fmt.Sprintf("DROP INDEX %s_%s_rid_idx", table, sqlTableCode),
+				fmt.Sprintf("DROP INDEX %s_%s_rid_idx", table, sqlTableRefresh),
+				fmt.Sprintf("DROP INDEX %s_%s_rid_idx", table, sqlTableAccess),
+			},
+		},
+		{
+			// Supports deleting expired sessions in batches.
+			Version: 4,
+			Up: []string{
+				fmt.Sprintf("CREATE INDEX %[1]s_%[2]s_rat_idx ON %[1]s_%[2]s (REQUESTED_AT)%[3]s", table, sqlTableAccess, indexStorage()),
+				fmt.Sprintf("CREATE INDEX %[1]s_%[2]s_rat_idx ON %[1]s_%[2]s (REQUESTED_AT)%[3]s", table, sqlTableRefresh, indexStorage()),
+				fmt.Sprintf("CREATE INDEX %[1]s_%[2]s_rat_idx ON %[1]s_%[2]s (REQUESTED_AT)%[3]s", table, sqlTableCode, indexStorage()),
+				fmt.Sprintf("CREATE INDEX %[1]s_%[2]s_rat_idx ON %[1]s_%[2]s (REQUESTED_AT)%[3]s", table, sqlTableOpenID, indexStorage()),
+			},
+			Down: []string{
+				fmt.Sprintf("DROP INDEX %s_%s_rat_idx", table, sqlTableOpenID),
+				fmt.Sprintf("DROP INDEX %s_%s_rat_idx", table, sqlTableCode),
+				fmt.Sprintf("DROP INDEX %s_%s_rat_idx", table, sqlTableRefresh),
+				fmt.Sprintf("DROP INDEX %s_%s_rat_idx", table, sqlTableAccess),
+			},
+		},
+		{
+			// Keeps rotated refresh tokens as inactive, so that their reuse can be detected, and stores the grant of
+			// access and refresh tokens, so that all tokens of a grant can be revoked. ORY Hydra assigns every token
+			// request a new request ID, so tokens issued before are grouped by their own request only.
+			Version: 5,
+			Up: []string{
+				fmt.Sprintf("ALTER TABLE %s_%s ADD (ACTIVE NUMBER(1) DEFAULT 1 NOT NULL)", table, sqlTableRefresh),
+				fmt.Sprintf("ALTER TABLE %s_%s ADD (GRANT_ID VARCHAR2 (255) NULL)", table, sqlTableAccess),
+				fmt.Sprintf("ALTER TABLE %s_%s ADD (GRANT_ID VARCHAR2 (255) NULL)", table, sqlTableRefresh),
+				fmt.Sprintf("UPDATE %s_%s SET GRANT_ID = REQUEST_ID WHERE GRANT_ID IS NULL", table, sqlTableAccess),
+				fmt.Sprintf("UPDATE %s_%s SET GRANT_ID = REQUEST_ID WHERE GRANT_ID IS NULL", table, sqlTableRefresh),
+				fmt.Sprintf("CREATE INDEX %[1]s_%[2]s_gid_idx ON %[1]s_%[2]s (GRANT_ID)%[3]s", table, sqlTableAccess, indexStorage()),
+				fmt.Sprintf("CREATE INDEX %[1]s_%[2]s_gid_idx ON %[1]s_%[2]s (GRANT_ID)%[3]s", table, sqlTableRefresh, indexStorage()),
+			},
+			Down: []string{
+				fmt.Sprintf("DROP INDEX %s_%s_gid_idx", table, sqlTableRefresh),
+				fmt.Sprintf("DROP INDEX %s_%s_gid_idx", table, sqlTableAccess),
+				fmt.Sprintf("ALTER TABLE %s_%s DROP COLUMN GRANT_ID", table, sqlTableRefresh),
+				fmt.Sprintf("ALTER TABLE %s_%s DROP COLUMN GRANT_ID", table, sqlTableAccess),
+				fmt.Sprintf("ALTER TABLE %s_%s DROP COLUMN ACTIVE", table, sqlTableRefresh),
+			},
+		},
+		{
+			// Keeps redeemed authorize codes with the time of their redemption, so that their replay can be detected.
+			Version: 6,
+			Up:      []string{fmt.Sprintf("ALTER TABLE %s_%s ADD (USED_AT TIMESTAMP NULL)", table, sqlTableCode)},
+			Down:    []string{fmt.Sprintf("ALTER TABLE %s_%s DROP COLUMN USED_AT", table, sqlTableCode)},
+		},
+		{
+			// Stores the hash of the subject of sessions in a column of its own and indexes it together with the
+			// client, so that all sessions of a subject or client can be revoked. Subjects are hashed because they are
+			// not limited in length, whereas client IDs are limited to the 255 characters of the client table, as
+			// longer keys may exceed the maximum key length of an index.
+			Version:  7,
+			Up:       fositeOwnerColumnsUp(table),
+			Down:     fositeOwnerColumnsDown(table),
+			Backfill: fositeSubjectHashBackfill(table),
+		},
+	}
+}
+
+func fositeOwnerColumnsUp(table string) []string {
+	var statements []string
+	for _, kind := range fositeTableKinds {
+		statements = append(statements,
+			fmt.Sprintf("ALTER TABLE %s_%s MODIFY (CLIENT_ID VARCHAR2 (255))", table, kind),
+			fmt.Sprintf("ALTER TABLE %s_%s ADD (SUBJECT_HASH VARCHAR2 (64) NULL)", table, kind),
+			fmt.Sprintf("CREATE INDEX %[1]s_%[2]s_cid_idx ON %[1]s_%[2]s (CLIENT_ID)%[3]s", table, kind, indexStorage()),
+			fmt.Sprintf("CREATE INDEX %[1]s_%[2]s_sub_idx ON %[1]s_%[2]s (SUBJECT_HASH)%[3]s", table, kind, indexStorage()),
+		)
+	}
+	return statements
+}
+
+func fositeOwnerColumnsDown(table string) []string {
+	var statements []string
+	for i := len(fositeTableKinds) - 1; i >= 0; i-- {
+		kind := fositeTableKinds[i]
+		statements = append(statements,
+			fmt.Sprintf("DROP INDEX %s_%s_sub_idx", table, kind),
+			fmt.Sprintf("DROP INDEX %s_%s_cid_idx", table, kind),
+			fmt.Sprintf("ALTER TABLE %s_%s DROP COLUMN SUBJECT_HASH", table, kind),
+			fmt.Sprintf("ALTER TABLE %s_%s MODIFY (CLIENT_ID VARCHAR2 (4000))", table, kind),
+		)
+	}
+	return statements
+}
+
+// subjectHashBackfillBatchSize is the number of sessions read at once while backfilling the subject hash.
+const subjectHashBackfillBatchSize = 1000
+
+// fositeSubjectHashBackfill stores the hash of the subject of sessions created before migration 7. The subject is
+// read from the session data, which SQL can not parse on every supported version of Oracle.
+func fositeSubjectHashBackfill(table string) func(ctx context.Context, r sqlRunner) error {
+	return func(ctx context.Context, r sqlRunner) error {
+		for _, kind := range fositeTableKinds {
+			if err := backfillSubjectHashes(ctx, r, table+"_"+kind); err != nil {
+				return errors.Wrapf(err, "Could not backfill the subject hashes of %s_%s", table, kind)
+			}
+		}
+		return nil
+	}
+}
+
+func backfillSubjectHashes(ctx context.Context, r sqlRunner, table string) error {
+	var last string
+	for {
+		// Sessions without subject keep a NULL hash, so the batches are paged by signature.
+		condition, args := "", []interface{}{}
+		if last != "" {
+			condition, args = " AND SIGNATURE > ?", append(args, last)
+		}
+		query := fmt.Sprintf(
+			"SELECT SIGNATURE, SESSION_DATA FROM (SELECT SIGNATURE, SESSION_DATA FROM %s WHERE SUBJECT_HASH IS NULL%s ORDER BY SIGNATURE) WHERE ROWNUM <= %d",
+			table, condition, subjectHashBackfillBatchSize,
+		)
+
+		hashes := map[string]string{}
+		n, err := func() (int, error) {
+			rows, err := r.QueryContext(ctx, r.Rebind(query), args...)
+			if err != nil {
+				return 0, classifyError(err)
+			}
+			defer rows.Close()
+
+			var n int
+			for ; rows.Next(); n++ {
+				var signature string
+				var session clob
+				if err := rows.Scan(&signature, &session); err != nil {
+					return 0, errors.WithStack(err)
+				}
+				if subject := sessionSubject([]byte(session)); subject != "" {
+					hashes[signature] = subjectHash(subject)
+				}
+				last = signature
+			}
+			return n, errors.WithStack(rows.Err())
+		}()
+		if err != nil {
+			return err
+		}
+
+		for signature, hash := range hashes {
+			if _, err := r.ExecContext(ctx, r.Rebind(fmt.Sprintf("UPDATE %s SET SUBJECT_HASH = ? WHERE SIGNATURE = ?", table)), hash, signature); err != nil {
+				return classifyError(err)
+			}
+		}
+
+		if n < subjectHashBackfillBatchSize {
+			return nil
+		}
+	}
+}
+
+// sessionSubject returns the subject of serialized session data, which is either a fosite.DefaultSession or an ORY
+// Hydra session embedding the OpenID Connect session as idToken. Session data which can not be parsed has no subject.
+func sessionSubject(data []byte) string {
+	var session struct {
+		Subject string
+		IDToken struct {
+			Subject string
+		} `json:"idToken"`
+	}
+	if err := json.Unmarshal(data, &session); err != nil {
+		return ""
+	} else if session.IDToken.Subject != "" {
+		return session.IDToken.Subject
+	}
+	return session.Subject
+}
+
+// subjectHash returns the hex encoded SHA-256 hash of a subject, which is stored and indexed instead of the subject,
+// as subjects may exceed the maximum key length of an index.
+func subjectHash(subject string) string {
+	sum := sha256.Sum256([]byte(subject))
+	return hex.EncodeToString(sum[:])
+}
+
+func fositeConvertColumns(table, dataType, definition, expression string) []string {
+	var statements []string
+	for _, kind := range []string{sqlTableAccess, sqlTableRefresh, sqlTableCode, sqlTableOpenID} {
+		for _, column := range []string{"FORM_DATA", "SESSION_DATA"} {
+			statements = append(statements, convertColumn(table+"_"+kind, column, dataType, definition, expression))
+		}
+	}
+	return statements
+}
+
+// fositeColumns and fositeIndexes list the columns and indexes only some of the token tables have.
+var (
+	fositeColumns = map[string][]ColumnDefinition{
+		sqlTableAccess:  {{"GRANT_ID", "VARCHAR2", 255, true}},
+		sqlTableRefresh: {{"ACTIVE", "NUMBER", 0, false}, {"GRANT_ID", "VARCHAR2", 255, true}},
+		sqlTableCode:    {{"USED_AT", "TIMESTAMP(6)", 0, true}},
+	}
+	fositeIndexes = map[string][][]string{
+		sqlTableAccess:  {{"GRANT_ID"}},
+		sqlTableRefresh: {{"GRANT_ID"}},
+	}
+)
+
+var fositeTables = func(table string) []TableDefinition {
+	var tables []TableDefinition
+	for _, kind := range []string{sqlTableAccess, sqlTableRefresh, sqlTableCode, sqlTableOpenID} {
+		tables = append(tables, TableDefinition{
+			Name: table + "_" + kind,
+			Columns: append([]ColumnDefinition{
+				{"SIGNATURE", "VARCHAR2", 255, false},
+				{"REQUEST_ID", "VARCHAR2", 255, false},
+				{"REQUESTED_AT", "TIMESTAMP(6)", 0, false},
+				{"CLIENT_ID", "VARCHAR2", 255, true},
+				{"SCOPE", "VARCHAR2", 4000, true},
+				{"GRANTED_SCOPE", "VARCHAR2", 4000, true},
+				{"FORM_DATA", "CLOB", 0, true},
+				{"SESSION_DATA", "CLOB", 0, true},
+				{"SUBJECT_HASH", "VARCHAR2", 64, true},
+			}, fositeColumns[kind]...),
+			PrimaryKey: []string{"SIGNATURE"},
+			Indexes:    append([][]string{{"REQUEST_ID"}, {"REQUESTED_AT"}, {"CLIENT_ID"}, {"SUBJECT_HASH"}}, fositeIndexes[kind]...),
+		})
+	}
+	return tables
 }
 
 const (
@@ -59,6 +322,7 @@ var sqlParams = []string{
 	"GRANTED_SCOPE",
 	"FORM_DATA",
 	"SESSION_DATA",
+	"SUBJECT_HASH",
 }
 
 type sqlData struct {
@@ -68,13 +332,46 @@ type sqlData struct {
 	Client        string    `db:"CLIENT_ID"`
 	Scopes        string    `db:"SCOPE"`
 	GrantedScopes string    `db:"GRANTED_SCOPE"`
-	Form          string    `db:"FORM_DATA"`
-	Session       string    `db:"SESSION_DATA"`
+	Form          clob      `db:"FORM_DATA"`
+	Session       clob      `db:"SESSION_DATA"`
+	SubjectHash   *string   `db:"SUBJECT_HASH"`
+}
+
+var grantSqlParams = append(append([]string{}, sqlParams...), "GRANT_ID")
+
+// grantSqlData is an access or refresh token session. Tokens issued for the same authorize code or by rotating the
+// same refresh token share the grant, whereas ORY Hydra assigns every token request a new request ID.
+type grantSqlData struct {
+	sqlData
+	Grant string `db:"GRANT_ID"`
+}
+
+// Tokens created by an earlier version of the plugin while migrating have no grant and are grouped by their request.
+const grantColumn = "NVL(GRANT_ID, REQUEST_ID) AS GRANT_ID"
+
+var refreshSqlParams = append(append([]string{}, sqlParams...), grantColumn, "ACTIVE")
+
+// refreshSqlData is a refresh token session. Rotated refresh tokens are kept as inactive.
+type refreshSqlData struct {
+	grantSqlData
+	Active int `db:"ACTIVE"`
+}
+
+var codeSqlParams = append(append([]string{}, sqlParams...), "USED_AT")
+
+// codeSqlData is an authorize code session. Redeemed authorize codes are kept with the time of their redemption.
+type codeSqlData struct {
+	sqlData
+	UsedAt *time.Time `db:"USED_AT"`
 }
 
 func fositeSqlSchemaFromRequest(SIGNATURE string, r fosite.Requester, logger logrus.FieldLogger) (*sqlData, error) {
+	var hash *string
 	if r.GetSession() == nil {
 		logger.Debugf("Got an empty session in fositeSqlSchemaFromRequest")
+	} else if subject := r.GetSession().GetSubject(); subject != "" {
+		h := subjectHash(subject)
+		hash = &h
 	}
 
 	session, err := json.Marshal(r.GetSession())
@@ -89,12 +386,13 @@ func fositeSqlSchemaFromRequest(SIGNATURE string, r fosite.Requester, logger log
 		Client:        r.GetClient().GetID(),
 		Scopes:        strings.Join([]string(r.GetRequestedScopes()), "|"),
 		GrantedScopes: strings.Join([]string(r.GetGrantedScopes()), "|"),
-		Form:          r.GetRequestForm().Encode(),
-		Session:       string(session),
+		Form:          clob(r.GetRequestForm().Encode()),
+		Session:       clob(session),
+		SubjectHash:   hash,
 	}, nil
 }
 
-func (s *sqlData) toRequest(session fosite.Session, cm client.Manager, logger logrus.FieldLogger) (*fosite.Request, error) {
+func (s *sqlData) toRequest(ctx context.Context, session fosite.Session, cm client.Manager, logger logrus.FieldLogger) (*fosite.Request, error) {
 	if session != nil {
 		if err := json.Unmarshal([]byte(s.Session), session); err != nil {
 			return nil, errors.Wrapf(err, "Could not unmarshal session data: %s", s.Session)
@@ -103,12 +401,12 @@ func (s *sqlData) toRequest(session fosite.Session, cm client.Manager, logger lo
 		logger.Debugf("Got an empty session in toRequest")
 	}
 
-	c, err := cm.GetClient(context.Background(), s.Client)
+	c, err := cm.GetClient(ctx, s.Client)
 	if err != nil {
 		return nil, err
 	}
 
-	val, err := url.ParseQuery(s.Form)
+	val, err := url.ParseQuery(string(s.Form))
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
@@ -126,168 +424,433 @@ func (s *sqlData) toRequest(session fosite.Session, cm client.Manager, logger lo
 	return r, nil
 }
 
-func (s *FositeStore) createSession(SIGNATURE string, requester fosite.Requester, table string) error {
+func (s *FositeStore) createSession(ctx context.Context, SIGNATURE string, requester fosite.Requester, table string) error {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	return s.createSessionTx(ctx, s.DB, SIGNATURE, requester, table, requester.GetID())
+}
+
+// createSessionTx creates a session within the transaction tx. Passing the database creates it in a transaction of
+// its own. Access and refresh tokens are created as part of the given grant.
+func (s *FositeStore) createSessionTx(ctx context.Context, tx sqlx.ExtContext, SIGNATURE string, requester fosite.Requester, table, grant string) error {
 	data, err := fositeSqlSchemaFromRequest(SIGNATURE, requester, s.L)
 	if err != nil {
 		return err
 	}
 
+	params, arg := sqlParams, interface{}(data)
+	if table == sqlTableAccess || table == sqlTableRefresh {
+		params, arg = grantSqlParams, &grantSqlData{sqlData: *data, Grant: grant}
+	}
+
 	query := fmt.Sprintf(
 		"INSERT INTO %s_%s (%s) VALUES (%s)",
 		s.GetTable(),
 		table,
-		strings.Join(sqlParams, ", "),
-		":"+strings.Join(sqlParams, ", :"),
+		strings.Join(params, ", "),
+		":"+strings.Join(params, ", :"),
 	)
-	if _, err := s.DB.NamedExec(query, data); err != nil {
-		return errors.WithStack(err)
+	if _, err := sqlx.NamedExecContext(ctx, tx, query, arg); err != nil {
+		return classifyError(err)
 	}
 	return nil
 }
 
-func (s *FositeStore) findSessionBySignature(SIGNATURE string, session fosite.Session, table string) (fosite.Requester, error) {
+func (s *FositeStore) findSessionBySignature(ctx context.Context, SIGNATURE string, session fosite.Session, table string) (fosite.Requester, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
 	var d sqlData
-	if err := s.DB.Get(&d, s.DB.Rebind(fmt.Sprintf("SELECT * FROM %s_%s WHERE SIGNATURE=?", s.GetTable(), table)), SIGNATURE); err == sql.ErrNoRows {
-		return nil, errors.Wrap(fosite.ErrNotFound, "")
+	if err := s.getSession(ctx, &d, SIGNATURE, table, sqlParams); err != nil {
+		return nil, err
+	}
+
+	return d.toRequest(ctx, session, s.Manager, s.L)
+}
+
+// getSession scans the given columns of a session into dest. The columns are listed explicitly, as the token tables
+// differ in the columns listed in fositeColumns.
+func (s *FositeStore) getSession(ctx context.Context, dest interface{}, SIGNATURE string, table string, columns []string) error {
+	query := s.DB.Rebind(fmt.Sprintf("SELECT %s FROM %s_%s WHERE SIGNATURE=?", strings.Join(columns, ", "), s.GetTable(), table))
+	if err := retryTransient(ctx, s.DB, func(conn *dbConn) error {
+		return conn.GetContext(ctx, dest, query, SIGNATURE)
+	}); err == sql.ErrNoRows {
+		return errors.Wrap(fosite.ErrNotFound, "")
 	} else if err != nil {
-		return nil, errors.WithStack(err)
+		return classifyError(err)
 	}
+	return nil
+}
+
+func (s *FositeStore) deleteSession(ctx context.Context, SIGNATURE string, table string) error {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
 
-	return d.toRequest(session, s.Manager, s.L)
+	return s.deleteSessionTx(ctx, s.DB, SIGNATURE, table)
 }
 
-func (s *FositeStore) deleteSession(SIGNATURE string, table string) error {
-	if _, err := s.DB.Exec(s.DB.Rebind(fmt.Sprintf("DELETE FROM %s_%s WHERE SIGNATURE=?", s.GetTable(), table)), SIGNATURE); err != nil {
-		return errors.WithStack(err)
+// deleteSessionTx deletes a session within the transaction tx, see createSessionTx.
+func (s *FositeStore) deleteSessionTx(ctx context.Context, tx sqlx.ExtContext, SIGNATURE string, table string) error {
+	if _, err := tx.ExecContext(ctx, tx.Rebind(fmt.Sprintf("DELETE FROM %s_%s WHERE SIGNATURE=?", s.GetTable(), table)), SIGNATURE); err != nil {
+		return classifyError(err)
 	}
 	return nil
 }
 
-func (s *FositeStore) CreateSchemas() (int, error) {
-	tx, err := s.DB.Begin()
+// transaction runs f in a transaction, which is committed if f succeeds and rolled back otherwise. The context passed
+// to f is limited to QUERY_TIMEOUT.
+func (s *FositeStore) transaction(ctx context.Context, f func(ctx context.Context, tx *sqlx.Tx) error) error {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	tx, err := s.DB.BeginTxx(ctx, nil)
 	if err != nil {
-		return 0, errors.WithStack(err)
-	}
-
-	for _, schema := range []string{
-		fositeSqlTemplate(sqlTableAccess, s.GetTable()),
-		fositeSqlTemplate(sqlTableRefresh, s.GetTable()),
-		fositeSqlTemplate(sqlTableCode, s.GetTable()),
-		fositeSqlTemplate(sqlTableOpenID, s.GetTable()),
-	} {
-		if _, err := tx.Exec(schema); err != nil {
-			if err := tx.Rollback(); err != nil {
-				return 0, errors.WithStack(err)
-			}
-			return 0, errors.Wrapf(err, "Could not migrate oauth2 sql schema: %s", schema)
+		return errors.Wrap(classifyError(err), "Could not begin transaction")
+	}
+
+	if err := f(ctx, tx); err != nil {
+		if re := tx.Rollback(); re != nil {
+			return errors.Wrap(err, re.Error())
 		}
+		return err
 	}
 
 	if err := tx.Commit(); err != nil {
-		if err := tx.Rollback(); err != nil {
-			return 0, errors.WithStack(err)
-		}
-		return 0, errors.WithStack(err)
+		return errors.Wrap(classifyError(err), "Could not commit transaction")
 	}
+	return nil
+}
 
-	return 1, nil
+func (s *FositeStore) MigrationSet() *MigrationSet {
+	return &MigrationSet{
+		Manager:    "oauth2",
+		Table:      s.GetTable(),
+		Migrations: fositeMigrations(s.GetTable()),
+		Tables:     fositeTables(s.GetTable()),
+		Lookups: []Lookup{
+			{
+				Name:  "revoke access tokens",
+				Query: fositeRevokeQuery(s.GetTable(), sqlTableAccess),
+				Index: fmt.Sprintf("%s_%s_rid_idx", s.GetTable(), sqlTableAccess),
+			},
+			{
+				Name:  "revoke refresh tokens",
+				Query: fositeRevokeQuery(s.GetTable(), sqlTableRefresh),
+				Index: fmt.Sprintf("%s_%s_rid_idx", s.GetTable(), sqlTableRefresh),
+			},
+			{
+				Name:  "revoke access tokens of a grant",
+				Query: fositeRevokeGrantQuery(s.GetTable(), sqlTableAccess),
+				Index: fmt.Sprintf("%s_%s_gid_idx", s.GetTable(), sqlTableAccess),
+			},
+			{
+				Name:  "revoke refresh tokens of a grant",
+				Query: fositeRevokeGrantQuery(s.GetTable(), sqlTableRefresh),
+				Index: fmt.Sprintf("%s_%s_gid_idx", s.GetTable(), sqlTableRefresh),
+			},
+			{
+				Name:  "delete expired access tokens",
+				Query: fositeDeleteExpiredQuery(s.GetTable(), sqlTableAccess),
+				Index: fmt.Sprintf("%s_%s_rat_idx", s.GetTable(), sqlTableAccess),
+			},
+			{
+				Name:  "revoke access tokens of a client",
+				Query: fositeRevokeByQuery(s.GetTable(), sqlTableAccess, "CLIENT_ID"),
+				Index: fmt.Sprintf("%s_%s_cid_idx", s.GetTable(), sqlTableAccess),
+			},
+			{
+				Name:  "revoke access tokens of a subject",
+				Query: fositeRevokeByQuery(s.GetTable(), sqlTableAccess, "SUBJECT_HASH"),
+				Index: fmt.Sprintf("%s_%s_sub_idx", s.GetTable(), sqlTableAccess),
+			},
+		},
+	}
 }
 
-func (s *FositeStore) CreateOpenIDConnectSession(_ context.Context, SIGNATURE string, requester fosite.Requester) error {
-	return s.createSession(SIGNATURE, requester, sqlTableOpenID)
+func (s *FositeStore) CreateSchemas() (int, error) {
+	n, err := (&Migrator{DB: s.DB}).Up(s.MigrationSet())
+	if err != nil {
+		return n, errors.Wrap(err, "Could not migrate oauth2 sql schema")
+	}
+	return n, nil
 }
 
-func (s *FositeStore) GetOpenIDConnectSession(_ context.Context, SIGNATURE string, requester fosite.Requester) (fosite.Requester, error) {
-	return s.findSessionBySignature(SIGNATURE, requester.GetSession(), sqlTableOpenID)
+func (s *FositeStore) CreateOpenIDConnectSession(ctx context.Context, SIGNATURE string, requester fosite.Requester) error {
+	return s.createSession(ctx, SIGNATURE, requester, sqlTableOpenID)
 }
 
-func (s *FositeStore) DeleteOpenIDConnectSession(_ context.Context, SIGNATURE string) error {
-	return s.deleteSession(SIGNATURE, sqlTableOpenID)
+func (s *FositeStore) GetOpenIDConnectSession(ctx context.Context, SIGNATURE string, requester fosite.Requester) (fosite.Requester, error) {
+	return s.findSessionBySignature(ctx, SIGNATURE, requester.GetSession(), sqlTableOpenID)
 }
 
-func (s *FositeStore) CreateAuthorizeCodeSession(_ context.Context, SIGNATURE string, requester fosite.Requester) error {
-	return s.createSession(SIGNATURE, requester, sqlTableCode)
+func (s *FositeStore) DeleteOpenIDConnectSession(ctx context.Context, SIGNATURE string) error {
+	return s.deleteSession(ctx, SIGNATURE, sqlTableOpenID)
 }
 
-func (s *FositeStore) GetAuthorizeCodeSession(_ context.Context, SIGNATURE string, session fosite.Session) (fosite.Requester, error) {
-	return s.findSessionBySignature(SIGNATURE, session, sqlTableCode)
+func (s *FositeStore) CreateAuthorizeCodeSession(ctx context.Context, SIGNATURE string, requester fosite.Requester) error {
+	return s.createSession(ctx, SIGNATURE, requester, sqlTableCode)
 }
 
-func (s *FositeStore) DeleteAuthorizeCodeSession(_ context.Context, SIGNATURE string) error {
-	return s.deleteSession(SIGNATURE, sqlTableCode)
+// GetAuthorizeCodeSession returns the session of an authorize code. If the code has been redeemed already, the session
+// is returned along with ErrAuthorizeCodeInvalidated. Reading the code never revokes anything, as it is also read to
+// introspect it and before the client is verified, see PersistAuthorizeCodeGrantSession.
+func (s *FositeStore) GetAuthorizeCodeSession(ctx context.Context, SIGNATURE string, session fosite.Session) (fosite.Requester, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	var d codeSqlData
+	if err := s.getSession(ctx, &d, SIGNATURE, sqlTableCode, codeSqlParams); err != nil {
+		return nil, err
+	}
+
+	request, err := d.toRequest(ctx, session, s.Manager, s.L)
+	if err != nil {
+		return nil, err
+	} else if d.UsedAt != nil {
+		return request, errors.Wrapf(ErrAuthorizeCodeInvalidated, "Authorize code of request %s was redeemed already", d.Request)
+	}
+	return request, nil
 }
 
-func (s *FositeStore) CreateAccessTokenSession(_ context.Context, SIGNATURE string, requester fosite.Requester) error {
-	return s.createSession(SIGNATURE, requester, sqlTableAccess)
+// revokeReplayedAuthorizeCode revokes all access and refresh tokens issued for an authorize code which has been
+// redeemed again, and returns the error reporting the replay.
+func (s *FositeStore) revokeReplayedAuthorizeCode(ctx context.Context, SIGNATURE, requestID string) error {
+	s.L.Warnf("The authorize code of request %s was redeemed again, revoking all tokens issued for it", requestID)
+	if err := s.revokeGrantTokens(ctx, SIGNATURE); err != nil {
+		return errors.Wrapf(err, "Could not revoke the tokens of request %s after its authorize code was replayed", requestID)
+	}
+	return errors.Wrapf(ErrAuthorizeCodeInvalidated, "Authorize code of request %s was redeemed already", requestID)
 }
 
-func (s *FositeStore) GetAccessTokenSession(_ context.Context, SIGNATURE string, session fosite.Session) (fosite.Requester, error) {
-	return s.findSessionBySignature(SIGNATURE, session, sqlTableAccess)
+// DeleteAuthorizeCodeSession invalidates an authorize code. The code is kept as redeemed rather than deleted, see
+// GetAuthorizeCodeSession.
+func (s *FositeStore) DeleteAuthorizeCodeSession(ctx context.Context, SIGNATURE string) error {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	_, err := s.invalidateAuthorizeCodeTx(ctx, s.DB, SIGNATURE)
+	return err
 }
 
-func (s *FositeStore) DeleteAccessTokenSession(_ context.Context, SIGNATURE string) error {
-	return s.deleteSession(SIGNATURE, sqlTableAccess)
+// invalidateAuthorizeCodeTx marks an authorize code as redeemed within the transaction tx and returns false if it was
+// redeemed already or does not exist.
+func (s *FositeStore) invalidateAuthorizeCodeTx(ctx context.Context, tx sqlx.ExtContext, SIGNATURE string) (bool, error) {
+	result, err := tx.ExecContext(ctx, tx.Rebind(fmt.Sprintf("UPDATE %s_%s SET USED_AT = ? WHERE SIGNATURE = ? AND USED_AT IS NULL", s.GetTable(), sqlTableCode)), time.Now().UTC(), SIGNATURE)
+	if err != nil {
+		return false, classifyError(err)
+	}
+
+	n, err := result.RowsAffected()
+	return n > 0, errors.WithStack(err)
 }
 
-func (s *FositeStore) CreateRefreshTokenSession(_ context.Context, SIGNATURE string, requester fosite.Requester) error {
-	return s.createSession(SIGNATURE, requester, sqlTableRefresh)
+// RevokeAuthorizeCodeTokens revokes all access and refresh tokens issued for an authorize code, including the tokens
+// issued by rotating its refresh token. The tokens are found by their grant, so they are revoked even after the code
+// itself has been deleted.
+func (s *FositeStore) RevokeAuthorizeCodeTokens(ctx context.Context, SIGNATURE string) error {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	return s.revokeGrantTokens(ctx, SIGNATURE)
 }
 
-func (s *FositeStore) GetRefreshTokenSession(_ context.Context, SIGNATURE string, session fosite.Session) (fosite.Requester, error) {
-	return s.findSessionBySignature(SIGNATURE, session, sqlTableRefresh)
+func (s *FositeStore) CreateAccessTokenSession(ctx context.Context, SIGNATURE string, requester fosite.Requester) error {
+	return s.createSession(ctx, SIGNATURE, requester, sqlTableAccess)
 }
 
-func (s *FositeStore) DeleteRefreshTokenSession(_ context.Context, SIGNATURE string) error {
-	return s.deleteSession(SIGNATURE, sqlTableRefresh)
+func (s *FositeStore) GetAccessTokenSession(ctx context.Context, SIGNATURE string, session fosite.Session) (fosite.Requester, error) {
+	return s.findSessionBySignature(ctx, SIGNATURE, session, sqlTableAccess)
 }
 
-func (s *FositeStore) CreateImplicitAccessTokenSession(ctx context.Context, SIGNATURE string, requester fosite.Requester) error {
-	return s.CreateAccessTokenSession(ctx, SIGNATURE, requester)
+func (s *FositeStore) DeleteAccessTokenSession(ctx context.Context, SIGNATURE string) error {
+	return s.deleteSession(ctx, SIGNATURE, sqlTableAccess)
 }
 
-func (s *FositeStore) PersistAuthorizeCodeGrantSession(ctx context.Context, authorizeCode, accessSignature, refreshSignature string, request fosite.Requester) error {
-	if err := s.DeleteAuthorizeCodeSession(ctx, authorizeCode); err != nil {
-		return err
-	} else if err := s.CreateAccessTokenSession(ctx, accessSignature, request); err != nil {
-		return err
+func (s *FositeStore) CreateRefreshTokenSession(ctx context.Context, SIGNATURE string, requester fosite.Requester) error {
+	return s.createSession(ctx, SIGNATURE, requester, sqlTableRefresh)
+}
+
+// GetRefreshTokenSession returns the session of a refresh token. If the token has been rotated already, the session is
+// returned along with ErrRefreshTokenInactive. Reading the token never revokes anything, as it is also read to
+// introspect or revoke it, see PersistRefreshTokenGrantSession.
+func (s *FositeStore) GetRefreshTokenSession(ctx context.Context, SIGNATURE string, session fosite.Session) (fosite.Requester, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	var d refreshSqlData
+	if err := s.getSession(ctx, &d, SIGNATURE, sqlTableRefresh, refreshSqlParams); err != nil {
+		return nil, err
 	}
 
-	if refreshSignature == "" {
-		return nil
+	request, err := d.toRequest(ctx, session, s.Manager, s.L)
+	if err != nil {
+		return nil, err
+	} else if d.Active == 0 {
+		return request, errors.Wrapf(ErrRefreshTokenInactive, "Refresh token of grant %s was rotated already", d.Grant)
 	}
+	return request, nil
+}
 
-	if err := s.CreateRefreshTokenSession(ctx, refreshSignature, request); err != nil {
-		return err
+// revokeReusedRefreshToken revokes all access and refresh tokens of the grant of a refresh token which has been used
+// after it was rotated, and returns the error reporting the reuse.
+func (s *FositeStore) revokeReusedRefreshToken(ctx context.Context, grant string) error {
+	s.L.Warnf("A rotated refresh token of grant %s was used again, revoking all tokens of the grant", grant)
+	if err := s.revokeGrantTokens(ctx, grant); err != nil {
+		return errors.Wrapf(err, "Could not revoke the tokens of grant %s after its refresh token was reused", grant)
 	}
+	return errors.Wrapf(ErrRefreshTokenInactive, "Refresh token of grant %s was used after it had been rotated", grant)
+}
+
+var fositeRevokeGrantQuery = func(table, kind string) string {
+	return fmt.Sprintf("DELETE FROM %s_%s WHERE GRANT_ID=?", table, kind)
+}
+
+// revokeGrantTokens revokes all access and refresh tokens of a grant in one transaction.
+func (s *FositeStore) revokeGrantTokens(ctx context.Context, grant string) error {
+	return s.transaction(ctx, func(ctx context.Context, tx *sqlx.Tx) error {
+		for _, table := range []string{sqlTableAccess, sqlTableRefresh} {
+			if _, err := tx.ExecContext(ctx, tx.Rebind(fositeRevokeGrantQuery(s.GetTable(), table)), grant); err != nil {
+				return classifyError(err)
+			}
+		}
+		return nil
+	})
+}
+
+func (s *FositeStore) DeleteRefreshTokenSession(ctx context.Context, SIGNATURE string) error {
+	return s.deleteSession(ctx, SIGNATURE, sqlTableRefresh)
+}
 
+func (s *FositeStore) CreateImplicitAccessTokenSession(ctx context.Context, SIGNATURE string, requester fosite.Requester) error {
+	return s.CreateAccessTokenSession(ctx, SIGNATURE, requester)
+}
+
+// PersistAuthorizeCodeGrantSession redeems the authorize code and creates the tokens issued for it in one
+// transaction, so that a failure can not leave a redeemed code without tokens. The tokens are created with the code
+// as their grant. If the code has been redeemed already, the tokens of the grant are revoked and
+// ErrAuthorizeCodeInvalidated is returned.
+func (s *FositeStore) PersistAuthorizeCodeGrantSession(ctx context.Context, authorizeCode, accessSignature, refreshSignature string, request fosite.Requester) error {
+	if err := s.transaction(ctx, func(ctx context.Context, tx *sqlx.Tx) error {
+		if ok, err := s.invalidateAuthorizeCodeTx(ctx, tx, authorizeCode); err != nil {
+			return err
+		} else if !ok {
+			return errors.WithStack(ErrAuthorizeCodeInvalidated)
+		}
+
+		if err := s.createSessionTx(ctx, tx, accessSignature, request, sqlTableAccess, authorizeCode); err != nil {
+			return err
+		} else if refreshSignature == "" {
+			return nil
+		}
+		return s.createSessionTx(ctx, tx, refreshSignature, request, sqlTableRefresh, authorizeCode)
+	}); errors.Cause(err) == ErrAuthorizeCodeInvalidated {
+		return s.revokeReplayedAuthorizeCode(ctx, authorizeCode, request.GetID())
+	} else if err != nil {
+		return err
+	}
 	return nil
 }
 
+// PersistRefreshTokenGrantSession rotates the refresh token and creates the access token in one transaction, so that
+// a failure can not leave a user without refresh token. The new tokens inherit the grant of the original refresh
+// token, which is kept as inactive. If it is not active anymore, it was reused and was most likely stolen, so all
+// tokens of the grant are revoked and ErrRefreshTokenInactive is returned.
 func (s *FositeStore) PersistRefreshTokenGrantSession(ctx context.Context, originalRefreshSignature, accessSignature, refreshSignature string, request fosite.Requester) error {
-	if err := s.DeleteRefreshTokenSession(ctx, originalRefreshSignature); err != nil {
-		return err
-	} else if err := s.CreateAccessTokenSession(ctx, accessSignature, request); err != nil {
-		return err
-	} else if err := s.CreateRefreshTokenSession(ctx, refreshSignature, request); err != nil {
-		return err
+	var original struct {
+		Grant  string `db:"GRANT_ID"`
+		Active int    `db:"ACTIVE"`
 	}
+	if err := s.transaction(ctx, func(ctx context.Context, tx *sqlx.Tx) error {
+		// Locking the original refresh token serializes concurrent rotations of the same token.
+		query := fmt.Sprintf("SELECT %s, ACTIVE FROM %s_%s WHERE SIGNATURE = ? FOR UPDATE", grantColumn, s.GetTable(), sqlTableRefresh)
+		if err := sqlx.GetContext(ctx, tx, &original, tx.Rebind(query), originalRefreshSignature); err == sql.ErrNoRows {
+			return errors.Wrap(fosite.ErrNotFound, "")
+		} else if err != nil {
+			return classifyError(err)
+		} else if original.Active == 0 {
+			return errors.WithStack(ErrRefreshTokenInactive)
+		}
 
+		if _, err := tx.ExecContext(ctx, tx.Rebind(fmt.Sprintf("UPDATE %s_%s SET ACTIVE = 0 WHERE SIGNATURE = ?", s.GetTable(), sqlTableRefresh)), originalRefreshSignature); err != nil {
+			return classifyError(err)
+		}
+
+		if err := s.createSessionTx(ctx, tx, accessSignature, request, sqlTableAccess, original.Grant); err != nil {
+			return err
+		}
+		return s.createSessionTx(ctx, tx, refreshSignature, request, sqlTableRefresh, original.Grant)
+	}); errors.Cause(err) == ErrRefreshTokenInactive {
+		return s.revokeReusedRefreshToken(ctx, original.Grant)
+	} else if err != nil {
+		return err
+	}
 	return nil
 }
 
 func (s *FositeStore) RevokeRefreshToken(ctx context.Context, id string) error {
-	return s.revokeSession(id, sqlTableRefresh)
+	return s.revokeSession(ctx, id, sqlTableRefresh)
 }
 
 func (s *FositeStore) RevokeAccessToken(ctx context.Context, id string) error {
-	return s.revokeSession(id, sqlTableAccess)
+	return s.revokeSession(ctx, id, sqlTableAccess)
+}
+
+var fositeRevokeQuery = func(table, kind string) string {
+	return fmt.Sprintf("DELETE FROM %s_%s WHERE REQUEST_ID=?", table, kind)
 }
 
-func (s *FositeStore) revokeSession(id string, table string) error {
-	if _, err := s.DB.Exec(s.DB.Rebind(fmt.Sprintf("DELETE FROM %s_%s WHERE REQUEST_ID=?", s.GetTable(), table)), id); err == sql.ErrNoRows {
+func (s *FositeStore) revokeSession(ctx context.Context, id string, table string) error {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	if _, err := s.DB.ExecContext(ctx, s.DB.Rebind(fositeRevokeQuery(s.GetTable(), table)), id); err == sql.ErrNoRows {
 		return errors.Wrap(fosite.ErrNotFound, "")
 	} else if err != nil {
-		return errors.WithStack(err)
+		return classifyError(err)
 	}
 	return nil
 }
+
+var fositeRevokeByQuery = func(table, kind, column string) string {
+	return fmt.Sprintf("DELETE FROM %s_%s WHERE %s=?", table, kind, column)
+}
+
+// RevokeClientTokens revokes all access and refresh tokens, authorize codes and OpenID Connect sessions of a client in
+// one transaction and returns the number of revoked sessions by table.
+func (s *FositeStore) RevokeClientTokens(ctx context.Context, clientID string) (map[string]int64, error) {
+	counts, err := s.revokeSessionsBy(ctx, "CLIENT_ID", clientID)
+	return counts, errors.Wrapf(err, "Could not revoke the sessions of client %s", clientID)
+}
+
+// RevokeSubjectTokens revokes all sessions of a subject like RevokeClientTokens. Sessions are found by the hash of
+// their subject.
+func (s *FositeStore) RevokeSubjectTokens(ctx context.Context, subject string) (map[string]int64, error) {
+	if subject == "" {
+		return nil, errors.New("The subject of the sessions to revoke must not be empty")
+	}
+	counts, err := s.revokeSessionsBy(ctx, "SUBJECT_HASH", subjectHash(subject))
+	return counts, errors.Wrapf(err, "Could not revoke the sessions of subject %s", subject)
+}
+
+func (s *FositeStore) revokeSessionsBy(ctx context.Context, column, value string) (map[string]int64, error) {
+	counts := map[string]int64{}
+	if err := s.transaction(ctx, func(ctx context.Context, tx *sqlx.Tx) error {
+		for _, table := range fositeTableKinds {
+			result, err := tx.ExecContext(ctx, tx.Rebind(fositeRevokeByQuery(s.GetTable(), table, column)), value)
+			if err != nil {
+				return classifyError(err)
+			}
+
+			n, err := result.RowsAffected()
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			counts[s.GetTable()+"_"+table] = n
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
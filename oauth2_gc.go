@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+var fositeDeleteExpiredQuery = func(table, kind string) string {
+	return fmt.Sprintf("DELETE FROM %s_%s WHERE REQUESTED_AT < ? AND ROWNUM <= ?", table, kind)
+}
+
+// DeleteExpired deletes the sessions of a token table requested before the given time in batches of at most
+// batchSize rows. Each batch is committed on its own, so that undo and locks stay small, and is followed by a pause
+// of batchInterval. Deleting stops if ctx is done, the sessions deleted so far are returned in any case.
+func (s *FositeStore) DeleteExpired(ctx context.Context, table string, before time.Time, batchSize int, batchInterval time.Duration) (int64, error) {
+	if batchSize <= 0 {
+		return 0, errors.Errorf("Batch size %d must be positive", batchSize)
+	}
+
+	var deleted int64
+	query := s.DB.Rebind(fositeDeleteExpiredQuery(s.GetTable(), table))
+	for {
+		n, err := s.deleteExpiredBatch(ctx, query, before, batchSize)
+		deleted += n
+		if err != nil {
+			return deleted, errors.Wrapf(err, "Could not delete expired sessions of %s_%s", s.GetTable(), table)
+		} else if n < int64(batchSize) {
+			return deleted, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return deleted, errors.WithStack(ctx.Err())
+		case <-time.After(batchInterval):
+		}
+	}
+}
+
+func (s *FositeStore) deleteExpiredBatch(ctx context.Context, query string, before time.Time, batchSize int) (int64, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	result, err := s.DB.ExecContext(ctx, query, before, batchSize)
+	if err != nil {
+		return 0, classifyError(err)
+	}
+
+	n, err := result.RowsAffected()
+	return n, errors.WithStack(err)
+}
+
+// GarbageCollector periodically deletes the sessions of the token tables which are older than the retention of
+// their table. Tables without retention are skipped.
+type GarbageCollector struct {
+	Store         *FositeStore
+	L             logrus.FieldLogger
+	Interval      time.Duration
+	BatchSize     int
+	BatchInterval time.Duration
+}
+
+// newGarbageCollector returns a garbage collector configured by the OAUTH2_GC_* settings.
+func newGarbageCollector(store *FositeStore, logger logrus.FieldLogger) *GarbageCollector {
+	return &GarbageCollector{
+		Store:         store,
+		L:             logger,
+		Interval:      viper.GetDuration(configOAuth2GCInterval),
+		BatchSize:     viper.GetInt(configOAuth2GCBatchSize),
+		BatchInterval: viper.GetDuration(configOAuth2GCBatchInterval),
+	}
+}
+
+// Collect deletes the expired sessions of all token tables once and returns the number of deleted sessions by table.
+func (c *GarbageCollector) Collect(ctx context.Context) (map[string]int64, error) {
+	counts := map[string]int64{}
+	now := time.Now().UTC()
+	for _, table := range fositeTableKinds {
+		retention := viper.GetDuration(fositeRetentions[table])
+		if retention <= 0 {
+			continue
+		}
+
+		n, err := c.Store.DeleteExpired(ctx, table, now.Add(-retention), c.BatchSize, c.BatchInterval)
+		counts[c.Store.GetTable()+"_"+table] = n
+		if err != nil {
+			return counts, err
+		}
+	}
+	return counts, nil
+}
+
+// Run collects garbage every interval until ctx is done. Failed runs are logged and retried in the next interval.
+func (c *GarbageCollector) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.Interval)
+	defer ticker.Stop()
+
+	for {
+		counts, err := c.Collect(ctx)
+		for table, n := range counts {
+			if n > 0 {
+				c.L.Infof("Deleted %d expired sessions of %s", n, table)
+			}
+		}
+		if ctx.Err() != nil {
+			c.L.Infof("Stopped deleting expired sessions")
+			return
+		} else if err != nil {
+			c.L.Errorf("Could not delete expired sessions: %s", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			c.L.Infof("Stopped deleting expired sessions")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// The garbage collector of the plugin runs in the background of the process. It is started by the first OAuth2
+// manager ORY Hydra creates, so that creating further managers does not start further collectors.
+var (
+	collector     *runningCollector
+	collectorLock sync.Mutex
+)
+
+type runningCollector struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// startGarbageCollector starts collecting the expired sessions of the store in the background, unless a garbage
+// collector is running already, in which case nil is returned. Otherwise the returned function stops the collector
+// and waits until it has returned, cancelling a batch which is being deleted.
+func startGarbageCollector(store *FositeStore, logger logrus.FieldLogger) (stop func()) {
+	collectorLock.Lock()
+	defer collectorLock.Unlock()
+	if collector != nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &runningCollector{cancel: cancel, done: make(chan struct{})}
+	go func() {
+		defer close(c.done)
+		newGarbageCollector(store, logger).Run(ctx)
+	}()
+	collector = c
+	return func() {
+		collectorLock.Lock()
+		if collector == c {
+			collector = nil
+		}
+		collectorLock.Unlock()
+
+		c.cancel()
+		<-c.done
+	}
+}
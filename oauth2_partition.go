@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// fositeTableKinds lists the token tables in the order they are maintained.
+var fositeTableKinds = []string{sqlTableAccess, sqlTableRefresh, sqlTableCode, sqlTableOpenID}
+
+// fositeRetentions maps the token tables to the configuration keys of their retention. Sessions are kept for at
+// least their retention after they have been requested, a retention of zero keeps sessions forever.
+var fositeRetentions = map[string]string{
+	sqlTableAccess:  configOAuth2RetentionAccess,
+	sqlTableRefresh: configOAuth2RetentionRefresh,
+	sqlTableCode:    configOAuth2RetentionCode,
+	sqlTableOpenID:  configOAuth2RetentionOpenID,
+}
+
+// expiredPartitionsQuery selects the partitions whose upper bound is not after a point in time. HIGH_VALUE is a LONG
+// column, which drivers do not fetch alike, so DBMS_XMLGEN converts it to text on the database and the bounds are
+// compared there.
+const expiredPartitionsQuery = `SELECT PARTITION_NAME FROM XMLTABLE('/ROWSET/ROW'
+	PASSING DBMS_XMLGEN.GETXMLTYPE(?)
+	COLUMNS PARTITION_NAME VARCHAR2 (128) PATH 'PARTITION_NAME',
+		PARTITION_POSITION NUMBER PATH 'PARTITION_POSITION',
+		HIGH_VALUE VARCHAR2 (4000) PATH 'HIGH_VALUE')
+WHERE PARTITION_POSITION > 1 AND UPPER(PARTITION_NAME) <> UPPER(?)
+	AND TO_TIMESTAMP(REGEXP_SUBSTR(HIGH_VALUE, '[0-9]{4}-[0-9]{2}-[0-9]{2} [0-9]{2}:[0-9]{2}:[0-9]{2}'), 'YYYY-MM-DD HH24:MI:SS') <= ?
+ORDER BY PARTITION_POSITION`
+
+// ExpiredPartitions returns the names of the partitions of a token table which only contain sessions requested
+// before the given time. Tables which are not partitioned have no expired partitions.
+func (s *FositeStore) ExpiredPartitions(table string, before time.Time) ([]string, error) {
+	partitions := fmt.Sprintf(
+		"SELECT PARTITION_NAME, PARTITION_POSITION, HIGH_VALUE FROM ALL_TAB_PARTITIONS WHERE TABLE_OWNER = %s AND TABLE_NAME = UPPER(%s)",
+		currentSchemaOwner, quoteLiteral(fmt.Sprintf("%s_%s", s.GetTable(), table)),
+	)
+
+	var expired []string
+	if err := s.DB.Select(&expired, s.DB.Rebind(expiredPartitionsQuery), partitions, initialPartition, before); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return expired, nil
+}
+
+// PartitionStatements returns the statements which interval partition the token tables created before
+// OAUTH2_PARTITION_INTERVAL was set. Tables which are partitioned already are skipped.
+func (s *FositeStore) PartitionStatements() ([]string, error) {
+	var statements []string
+	for _, table := range fositeTableKinds {
+		name := fmt.Sprintf("%s_%s", s.GetTable(), table)
+		statement := repartitionTable(name, configOAuth2PartitionInterval, "REQUESTED_AT")
+		if statement == "" {
+			return nil, errors.Errorf("%s must be set to partition the token tables", configOAuth2PartitionInterval)
+		}
+
+		if partitioned, err := objectExists(s.DB, objectPartitions, name); err != nil {
+			return nil, err
+		} else if !partitioned {
+			statements = append(statements, statement)
+		}
+	}
+	return statements, nil
+}
+
+// DropPartition drops a partition of a token table including all sessions it contains. Global indexes are
+// maintained while the partition is dropped, so they remain usable.
+func (s *FositeStore) DropPartition(table, partition string) error {
+	query := fmt.Sprintf(`ALTER TABLE %s_%s DROP PARTITION "%s" UPDATE GLOBAL INDEXES`, s.GetTable(), table, strings.Replace(partition, `"`, "", -1))
+	if _, err := s.DB.Exec(query); err != nil {
+		return errors.Wrapf(err, "Could not drop partition %s of %s_%s", partition, s.GetTable(), table)
+	}
+	return nil
+}
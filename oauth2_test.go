@@ -1,14 +1,20 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/Sirupsen/logrus"
 	"github.com/ory/fosite"
 	"github.com/ory/hydra/client"
 	"github.com/ory/hydra/oauth2"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
 )
 
 var oauth2Manager *FositeStore
@@ -16,7 +22,7 @@ var oauth2Manager *FositeStore
 func init() {
 	db := connect(os.Getenv("ORACLE_DSN"))
 	cm := &client.MemoryManager{
-		Clients: map[string]client.Client{"foobar": {ID: "foobar"}},
+		Clients: map[string]client.Client{"foobar": {ID: "foobar"}, "revoked": {ID: "revoked"}},
 		Hasher:  &fosite.BCrypt{},
 	}
 	oauth2Manager = &FositeStore{
@@ -50,3 +56,316 @@ func TestCreateGetDeleteRefreshTokenSession(t *testing.T) {
 func TestRevokeRefreshToken(t *testing.T) {
 	oauth2.TestHelperRevokeRefreshToken(oauth2Manager)(t)
 }
+
+func newTestRequest(id string) *fosite.Request {
+	return &fosite.Request{
+		ID:          id,
+		RequestedAt: time.Now().Round(time.Second),
+		Client:      &client.Client{ID: "foobar"},
+		Session:     &fosite.DefaultSession{},
+	}
+}
+
+// TestRevokeReusedGrants issues the tokens of a grant, looks up a token which has been used already and uses it again.
+// The lookup must return the session without revoking anything, while using the token again revokes all tokens of the
+// grant.
+func TestRevokeReusedGrants(t *testing.T) {
+	ctx := context.Background()
+	s := oauth2Manager
+	for k, c := range []struct {
+		issue    func(p string) error
+		lookup   func(p string) (fosite.Requester, error)
+		reuse    func(p string) error
+		expected error
+		access   []string
+		refresh  []string
+	}{
+		{
+			issue: func(p string) error {
+				if err := s.CreateAccessTokenSession(ctx, p+"access-1", newTestRequest(p+"1")); err != nil {
+					return err
+				} else if err := s.CreateRefreshTokenSession(ctx, p+"refresh-1", newTestRequest(p+"1")); err != nil {
+					return err
+				} else if err := s.PersistRefreshTokenGrantSession(ctx, p+"refresh-1", p+"access-2", p+"refresh-2", newTestRequest(p+"2")); err != nil {
+					return err
+				}
+				return s.PersistRefreshTokenGrantSession(ctx, p+"refresh-2", p+"access-3", p+"refresh-3", newTestRequest(p+"3"))
+			},
+			lookup: func(p string) (fosite.Requester, error) {
+				return s.GetRefreshTokenSession(ctx, p+"refresh-1", &fosite.DefaultSession{})
+			},
+			reuse: func(p string) error {
+				return s.PersistRefreshTokenGrantSession(ctx, p+"refresh-1", p+"access-4", p+"refresh-4", newTestRequest(p+"4"))
+			},
+			expected: ErrRefreshTokenInactive,
+			access:   []string{"access-1", "access-2", "access-3", "access-4"},
+			refresh:  []string{"refresh-2", "refresh-3", "refresh-4"},
+		},
+		{
+			issue: func(p string) error {
+				if err := s.CreateAuthorizeCodeSession(ctx, p+"code", newTestRequest(p+"1")); err != nil {
+					return err
+				} else if err := s.PersistAuthorizeCodeGrantSession(ctx, p+"code", p+"access-1", p+"refresh-1", newTestRequest(p+"2")); err != nil {
+					return err
+				}
+				return s.PersistRefreshTokenGrantSession(ctx, p+"refresh-1", p+"access-2", p+"refresh-2", newTestRequest(p+"3"))
+			},
+			lookup: func(p string) (fosite.Requester, error) {
+				return s.GetAuthorizeCodeSession(ctx, p+"code", &fosite.DefaultSession{})
+			},
+			reuse: func(p string) error {
+				return s.PersistAuthorizeCodeGrantSession(ctx, p+"code", p+"access-3", "", newTestRequest(p+"4"))
+			},
+			expected: ErrAuthorizeCodeInvalidated,
+			access:   []string{"access-1", "access-2", "access-3"},
+			refresh:  []string{"refresh-1", "refresh-2"},
+		},
+	} {
+		p := fmt.Sprintf("reused-%d-", k)
+		if err := c.issue(p); err != nil {
+			t.Fatalf("Case %d: could not issue the tokens of the grant: %s", k, err)
+		}
+
+		if got, err := c.lookup(p); errors.Cause(err) != c.expected {
+			t.Fatalf("Case %d: expected the lookup to fail with %s but got %v", k, c.expected, err)
+		} else if got == nil || got.GetID() != p+"1" {
+			t.Fatalf("Case %d: expected the lookup to return the session but got %v", k, got)
+		} else if _, err := s.GetAccessTokenSession(ctx, p+c.access[1], &fosite.DefaultSession{}); err != nil {
+			t.Fatalf("Case %d: expected the lookup not to revoke the grant but got %s", k, err)
+		}
+
+		if err := c.reuse(p); errors.Cause(err) != c.expected {
+			t.Fatalf("Case %d: expected using the token again to fail with %s but got %v", k, c.expected, err)
+		}
+		for _, signature := range c.access {
+			if _, err := s.GetAccessTokenSession(ctx, p+signature, &fosite.DefaultSession{}); errors.Cause(err) != fosite.ErrNotFound {
+				t.Errorf("Case %d: expected %s to be revoked but got %v", k, signature, err)
+			}
+		}
+		for _, signature := range c.refresh {
+			if _, err := s.GetRefreshTokenSession(ctx, p+signature, &fosite.DefaultSession{}); errors.Cause(err) != fosite.ErrNotFound {
+				t.Errorf("Case %d: expected %s to be revoked but got %v", k, signature, err)
+			}
+		}
+	}
+}
+
+func TestSessionDataExceedingVarchar(t *testing.T) {
+	ctx := context.Background()
+	subject := strings.Repeat("s", 10000)
+	request := &fosite.Request{
+		ID:          "large-session",
+		RequestedAt: time.Now().Round(time.Second),
+		Client:      &client.Client{ID: "foobar"},
+		Form:        map[string][]string{"claims": {strings.Repeat("c", 5000)}},
+		Session:     &fosite.DefaultSession{Subject: subject},
+	}
+
+	if err := oauth2Manager.CreateAccessTokenSession(ctx, "large-session", request); err != nil {
+		t.Fatalf("Could not create session: %s", err)
+	}
+
+	got, err := oauth2Manager.GetAccessTokenSession(ctx, "large-session", &fosite.DefaultSession{})
+	if err != nil {
+		t.Fatalf("Could not get session: %s", err)
+	} else if got.GetSession().GetSubject() != subject {
+		t.Fatalf("Expected session data of %d characters to round trip", len(subject))
+	} else if got.GetRequestForm().Get("claims") != request.Form.Get("claims") {
+		t.Fatal("Expected form data to round trip")
+	}
+}
+
+func TestPersistAuthorizeCodeGrantSessionRollsBack(t *testing.T) {
+	ctx := context.Background()
+	request := &fosite.Request{
+		ID:          "atomic-grant",
+		RequestedAt: time.Now().Round(time.Second),
+		Client:      &client.Client{ID: "foobar"},
+		Session:     &fosite.DefaultSession{Subject: "peter"},
+	}
+
+	if err := oauth2Manager.CreateAuthorizeCodeSession(ctx, "atomic-code", request); err != nil {
+		t.Fatalf("Could not create authorize code: %s", err)
+	} else if err := oauth2Manager.CreateRefreshTokenSession(ctx, "atomic-refresh", request); err != nil {
+		t.Fatalf("Could not create refresh token: %s", err)
+	}
+
+	// The refresh token exists already, so creating it fails after the code was consumed and the access token created.
+	if err := oauth2Manager.PersistAuthorizeCodeGrantSession(ctx, "atomic-code", "atomic-access", "atomic-refresh", request); errors.Cause(err) != ErrConflict {
+		t.Fatalf("Expected ErrConflict but got %v", err)
+	}
+
+	if _, err := oauth2Manager.GetAuthorizeCodeSession(ctx, "atomic-code", &fosite.DefaultSession{}); err != nil {
+		t.Fatalf("Expected the authorize code to be kept but got %s", err)
+	} else if _, err := oauth2Manager.GetAccessTokenSession(ctx, "atomic-access", &fosite.DefaultSession{}); errors.Cause(err) != fosite.ErrNotFound {
+		t.Fatalf("Expected the access token to be rolled back but got %v", err)
+	}
+}
+
+func TestDeleteExpired(t *testing.T) {
+	ctx := context.Background()
+	for signature, requestedAt := range map[string]time.Time{
+		"expired-1": time.Now().Add(-48 * time.Hour),
+		"expired-2": time.Now().Add(-25 * time.Hour),
+		"active":    time.Now(),
+	} {
+		request := &fosite.Request{
+			ID:          "gc-" + signature,
+			RequestedAt: requestedAt.UTC().Round(time.Second),
+			Client:      &client.Client{ID: "foobar"},
+			Session:     &fosite.DefaultSession{},
+		}
+		if err := oauth2Manager.CreateOpenIDConnectSession(ctx, signature, request); err != nil {
+			t.Fatalf("Could not create session %s: %s", signature, err)
+		}
+	}
+
+	n, err := oauth2Manager.DeleteExpired(ctx, sqlTableOpenID, time.Now().UTC().Add(-24*time.Hour), 1, 0)
+	if err != nil {
+		t.Fatalf("Could not delete expired sessions: %s", err)
+	} else if n != 2 {
+		t.Fatalf("Expected 2 expired sessions to be deleted but got %d", n)
+	}
+
+	request := &fosite.Request{Session: &fosite.DefaultSession{}}
+	if _, err := oauth2Manager.GetOpenIDConnectSession(ctx, "active", request); err != nil {
+		t.Fatalf("Expected the active session to be kept but got %s", err)
+	} else if _, err := oauth2Manager.GetOpenIDConnectSession(ctx, "expired-1", request); errors.Cause(err) != fosite.ErrNotFound {
+		t.Fatalf("Expected the expired session to be deleted but got %v", err)
+	}
+}
+
+func TestCollectSkipsTablesWithoutRetention(t *testing.T) {
+	for _, key := range fositeRetentions {
+		defer viper.Set(key, nil)
+	}
+	viper.Set(configOAuth2RetentionOpenID, "24h")
+
+	ctx := context.Background()
+	store := &FositeStore{Manager: oauth2Manager.Manager, DB: oauth2Manager.DB, L: oauth2Manager.L, Table: randomTableName("oauth2")}
+	if _, err := store.CreateSchemas(); err != nil {
+		t.Fatalf("Could not create oauth2 schema: %s", err)
+	}
+
+	request := &fosite.Request{
+		ID:          "gc-retention",
+		RequestedAt: time.Now().UTC().Add(-48 * time.Hour).Round(time.Second),
+		Client:      &client.Client{ID: "foobar"},
+		Session:     &fosite.DefaultSession{},
+	}
+	if err := store.CreateAccessTokenSession(ctx, "gc-access", request); err != nil {
+		t.Fatalf("Could not create access token: %s", err)
+	} else if err := store.CreateOpenIDConnectSession(ctx, "gc-openid", request); err != nil {
+		t.Fatalf("Could not create OpenID Connect session: %s", err)
+	}
+
+	counts, err := (&GarbageCollector{Store: store, L: store.L, BatchSize: 10}).Collect(ctx)
+	if err != nil {
+		t.Fatalf("Could not collect garbage: %s", err)
+	} else if counts[store.GetTable()+"_"+sqlTableOpenID] != 1 {
+		t.Fatalf("Expected the expired OpenID Connect session to be deleted but got %v", counts)
+	} else if _, ok := counts[store.GetTable()+"_"+sqlTableAccess]; ok {
+		t.Fatalf("Expected the access tokens without a retention to be skipped but got %v", counts)
+	}
+
+	if _, err := store.GetAccessTokenSession(ctx, "gc-access", &fosite.DefaultSession{}); err != nil {
+		t.Fatalf("Expected the access token without a retention to be kept but got %s", err)
+	}
+}
+
+func TestGarbageCollectorStartsOnce(t *testing.T) {
+	defer viper.Set(configOAuth2GCInterval, nil)
+	viper.Set(configOAuth2GCInterval, "1h")
+
+	stop := startGarbageCollector(oauth2Manager, oauth2Manager.L)
+	if stop == nil {
+		t.Fatal("Expected the garbage collector to be started")
+	} else if startGarbageCollector(oauth2Manager, oauth2Manager.L) != nil {
+		t.Fatal("Expected the running garbage collector not to be started again")
+	}
+
+	stop()
+	if stop = startGarbageCollector(oauth2Manager, oauth2Manager.L); stop == nil {
+		t.Fatal("Expected the stopped garbage collector to be started again")
+	}
+	stop()
+}
+
+func TestRevokeClientAndSubjectTokens(t *testing.T) {
+	ctx := context.Background()
+	for _, session := range []struct {
+		signature, client, subject, table string
+	}{
+		{"revoked-access", "revoked", "alice", sqlTableAccess},
+		{"revoked-refresh", "revoked", "alice", sqlTableRefresh},
+		{"revoked-code", "foobar", "bob", sqlTableCode},
+		{"revoked-openid", "foobar", "alice", sqlTableOpenID},
+	} {
+		request := &fosite.Request{
+			ID:          session.signature,
+			RequestedAt: time.Now().Round(time.Second),
+			Client:      &client.Client{ID: session.client},
+			Session:     &fosite.DefaultSession{Subject: session.subject},
+		}
+		if err := oauth2Manager.createSession(ctx, session.signature, request, session.table); err != nil {
+			t.Fatalf("Could not create session %s: %s", session.signature, err)
+		}
+	}
+
+	table := oauth2Manager.GetTable()
+	if counts, err := oauth2Manager.RevokeSubjectTokens(ctx, "bob"); err != nil {
+		t.Fatalf("Could not revoke the tokens of bob: %s", err)
+	} else if counts[table+"_"+sqlTableCode] != 1 {
+		t.Fatalf("Expected the authorize code of bob to be revoked but got %v", counts)
+	}
+
+	if counts, err := oauth2Manager.RevokeClientTokens(ctx, "revoked"); err != nil {
+		t.Fatalf("Could not revoke the tokens of the client: %s", err)
+	} else if counts[table+"_"+sqlTableAccess] != 1 || counts[table+"_"+sqlTableRefresh] != 1 {
+		t.Fatalf("Expected the access and refresh token of the client to be revoked but got %v", counts)
+	} else if _, err := oauth2Manager.GetAccessTokenSession(ctx, "revoked-access", &fosite.DefaultSession{}); errors.Cause(err) != fosite.ErrNotFound {
+		t.Fatalf("Expected the access token to be revoked but got %v", err)
+	}
+
+	request := &fosite.Request{Session: &fosite.DefaultSession{}}
+	if _, err := oauth2Manager.GetOpenIDConnectSession(ctx, "revoked-openid", request); err != nil {
+		t.Fatalf("Expected the session of another client to be kept but got %s", err)
+	} else if counts, err := oauth2Manager.RevokeSubjectTokens(ctx, "alice"); err != nil {
+		t.Fatalf("Could not revoke the tokens of alice: %s", err)
+	} else if counts[table+"_"+sqlTableOpenID] != 1 {
+		t.Fatalf("Expected the OpenID Connect session of alice to be revoked but got %v", counts)
+	} else if _, err := oauth2Manager.GetOpenIDConnectSession(ctx, "revoked-openid", request); errors.Cause(err) != fosite.ErrNotFound {
+		t.Fatalf("Expected the OpenID Connect session to be revoked but got %v", err)
+	}
+}
+
+func TestSubjectHashBackfill(t *testing.T) {
+	ctx := context.Background()
+	subject := strings.Repeat("b", 10000)
+	for signature, session := range map[string]fosite.Session{
+		"backfilled-hydra":  oauth2.NewSession(subject),
+		"backfilled-fosite": &fosite.DefaultSession{Subject: subject},
+		"backfilled-none":   &fosite.DefaultSession{},
+	} {
+		request := newTestRequest(signature)
+		request.Session = session
+		if err := oauth2Manager.CreateAccessTokenSession(ctx, signature, request); err != nil {
+			t.Fatalf("Could not create session %s: %s", signature, err)
+		}
+	}
+
+	table := oauth2Manager.GetTable()
+	if _, err := oauth2Manager.DB.Exec(fmt.Sprintf("UPDATE %s_%s SET SUBJECT_HASH = NULL WHERE SIGNATURE LIKE 'backfilled-%%'", table, sqlTableAccess)); err != nil {
+		t.Fatalf("Could not remove the subject hashes: %s", err)
+	} else if err := fositeSubjectHashBackfill(table)(ctx, oauth2Manager.DB); err != nil {
+		t.Fatalf("Could not backfill the subject hashes: %s", err)
+	}
+
+	if counts, err := oauth2Manager.RevokeSubjectTokens(ctx, subject); err != nil {
+		t.Fatalf("Could not revoke the tokens of the subject: %s", err)
+	} else if counts[table+"_"+sqlTableAccess] != 2 {
+		t.Fatalf("Expected both sessions of the subject to be revoked but got %v", counts)
+	} else if _, err := oauth2Manager.GetAccessTokenSession(ctx, "backfilled-none", &fosite.DefaultSession{}); err != nil {
+		t.Fatalf("Expected the session without subject to be kept but got %s", err)
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"crypto/sha256"
 	"database/sql"
 	"encoding/json"
@@ -11,6 +12,7 @@ import (
 	. "github.com/ory/ladon"
 	"github.com/ory/ladon/compiler"
 	"github.com/pkg/errors"
+	"github.com/spf13/viper"
 )
 
 var policySchemas = func(table string) []string {
@@ -20,62 +22,245 @@ var policySchemas = func(table string) []string {
 		DESCRIPTION  VARCHAR2 (4000) NULL,
 		EFFECT       VARCHAR2 (4000) NOT NULL,
 		CONDITIONS 	 long raw NULL,
-		CONSTRAINT %[1]s_p_pk_idx PRIMARY KEY (ID)
-	)`, table),
+		CONSTRAINT %[1]s_p_pk_idx PRIMARY KEY (ID)%[2]s
+	)%[3]s`, table, constraintStorage(), tableStorage()),
 		// subject
 		fmt.Sprintf(`CREATE TABLE %s_s (
 		ID          varchar(64) NOT NULL,
 		HAS_REGEX   CHAR(1 BYTE) NOT NULL,
-		COMPILED 	varchar(511) NOT NULL UNIQUE,
-		TEMPLATE 	varchar(511) NOT NULL UNIQUE,
-		CONSTRAINT %[1]s_s_pk_idx PRIMARY KEY (ID)
-	)`, table),
+		COMPILED 	varchar(511) NOT NULL UNIQUE%[2]s,
+		TEMPLATE 	varchar(511) NOT NULL UNIQUE%[2]s,
+		CONSTRAINT %[1]s_s_pk_idx PRIMARY KEY (ID)%[2]s
+	)%[3]s`, table, constraintStorage(), tableStorage()),
 		// action
 		fmt.Sprintf(`CREATE TABLE %s_a (
 		ID       	varchar(64) NOT NULL,
 		HAS_REGEX   CHAR(1 BYTE) NOT NULL,
-		COMPILED 	varchar(511) NOT NULL UNIQUE,
-		TEMPLATE 	varchar(511) NOT NULL UNIQUE,
-		CONSTRAINT %[1]s_a_pk_idx PRIMARY KEY (ID)
-	)`, table),
+		COMPILED 	varchar(511) NOT NULL UNIQUE%[2]s,
+		TEMPLATE 	varchar(511) NOT NULL UNIQUE%[2]s,
+		CONSTRAINT %[1]s_a_pk_idx PRIMARY KEY (ID)%[2]s
+	)%[3]s`, table, constraintStorage(), tableStorage()),
 		// resource
 		fmt.Sprintf(`CREATE TABLE %s_r (
 		ID       	varchar(64) NOT NULL,
 		HAS_REGEX   CHAR(1 BYTE) NOT NULL,
-		COMPILED 	varchar(511) NOT NULL UNIQUE,
-		TEMPLATE 	varchar(511) NOT NULL UNIQUE,
-		CONSTRAINT %[1]s_r_pk_idx PRIMARY KEY (ID)
-	)`, table),
+		COMPILED 	varchar(511) NOT NULL UNIQUE%[2]s,
+		TEMPLATE 	varchar(511) NOT NULL UNIQUE%[2]s,
+		CONSTRAINT %[1]s_r_pk_idx PRIMARY KEY (ID)%[2]s
+	)%[3]s`, table, constraintStorage(), tableStorage()),
 		// subject to policy
 		fmt.Sprintf(`CREATE TABLE %[1]s_sr (
 		POLICY 		varchar(255) NOT NULL,
 		SUBJECT 	varchar(64) NOT NULL,
 
-		CONSTRAINT %[1]s_sr_pk_idx PRIMARY KEY (POLICY, SUBJECT),
+		CONSTRAINT %[1]s_sr_pk_idx PRIMARY KEY (POLICY, SUBJECT)%[2]s,
 		CONSTRAINT %[1]s_srp_fk FOREIGN KEY (POLICY) REFERENCES %[1]s_p (ID) ON DELETE CASCADE,
 		CONSTRAINT %[1]s_srs_fk FOREIGN KEY (SUBJECT) REFERENCES %[1]s_s (ID) ON DELETE CASCADE
-	)`, table),
+	)%[3]s`, table, constraintStorage(), tableStorage()),
 		// action to policy
 		fmt.Sprintf(`CREATE TABLE %[1]s_ar (
 		POLICY 		varchar(255) NOT NULL,
 		ACTION_ID 		varchar(64) NOT NULL,
 
-		CONSTRAINT %[1]s_ar_pk_idx PRIMARY KEY (POLICY, ACTION_ID),
+		CONSTRAINT %[1]s_ar_pk_idx PRIMARY KEY (POLICY, ACTION_ID)%[2]s,
 		CONSTRAINT %[1]s_arp_fk FOREIGN KEY (POLICY) REFERENCES %[1]s_p (ID) ON DELETE CASCADE,
 		CONSTRAINT %[1]s_ara_fk FOREIGN KEY (ACTION_ID) REFERENCES %[1]s_a (ID) ON DELETE CASCADE
-	)`, table),
+	)%[3]s`, table, constraintStorage(), tableStorage()),
 		// resource to policy
 		fmt.Sprintf(`CREATE TABLE %[1]s_rr (
 		POLICY 		varchar(255) NOT NULL,
 		RESOURCE_ID	varchar(64) NOT NULL,
 
-		CONSTRAINT %[1]s_rr_pk_idx PRIMARY KEY (POLICY, RESOURCE_ID),
+		CONSTRAINT %[1]s_rr_pk_idx PRIMARY KEY (POLICY, RESOURCE_ID)%[2]s,
 		CONSTRAINT %[1]s_rrp_fk FOREIGN KEY (POLICY) REFERENCES %[1]s_p (ID) ON DELETE CASCADE,
 		CONSTRAINT %[1]s_rrr_fk FOREIGN KEY (RESOURCE_ID) REFERENCES %[1]s_r (ID) ON DELETE CASCADE
-	)`, table),
+	)%[3]s`, table, constraintStorage(), tableStorage()),
 	}
 }
 
+// Conditions are stored as BLOB, or with POLICY_CONDITIONS set to JSON as a CLOB checked to hold JSON. The latter
+// requires Oracle 12.2 or later.
+const (
+	policyConditionsBLOB = "BLOB"
+	policyConditionsJSON = "JSON"
+)
+
+var policyMigrations = func(table string) []*Migration {
+	// The fourth migration stores the conditions as configured by POLICY_CONDITIONS. Reverting it always restores
+	// the BLOB of the second migration, which converts the LONG RAW of the first migration in place.
+	blob := []string{
+		fmt.Sprintf("ALTER TABLE %[1]s_p DROP CONSTRAINT %[1]s_p_json", table),
+		convertColumnWith(table+"_p", "CONDITIONS", "BLOB", "BLOB NULL", policyConditionsToBLOB(table)),
+	}
+	conditions := blob
+	if strings.ToUpper(viper.GetString(configPolicyConditions)) == policyConditionsJSON {
+		conditions = []string{
+			convertColumn(table+"_p", "CONDITIONS", "CLOB", "CLOB NULL", "TO_CLOB(%s, NLS_CHARSET_ID('AL32UTF8'))"),
+			fmt.Sprintf("ALTER TABLE %[1]s_p ADD CONSTRAINT %[1]s_p_json CHECK (CONDITIONS IS JSON)", table),
+		}
+	}
+
+	return []*Migration{
+		{
+			Version: 1,
+			Up:      policySchemas(table),
+			Down: []string{
+				fmt.Sprintf("DROP TABLE %s_rr", table),
+				fmt.Sprintf("DROP TABLE %s_ar", table),
+				fmt.Sprintf("DROP TABLE %s_sr", table),
+				fmt.Sprintf("DROP TABLE %s_r", table),
+				fmt.Sprintf("DROP TABLE %s_a", table),
+				fmt.Sprintf("DROP TABLE %s_s", table),
+				fmt.Sprintf("DROP TABLE %s_p", table),
+			},
+		},
+		{
+			Version: 2,
+			Up:      []string{modifyLongColumn(table+"_p", "CONDITIONS", "BLOB")},
+			Down:    []string{convertColumnWith(table+"_p", "CONDITIONS", "LONG RAW", "LONG RAW NULL", policyConditionsToLongRaw(table))},
+		},
+		{
+			// Supports finding the policies of templates, which are not the leading columns of the primary keys.
+			Version: 3,
+			Up: []string{
+				fmt.Sprintf("CREATE INDEX %[1]s_sr_s_idx ON %[1]s_sr (SUBJECT)%[2]s", table, indexStorage()),
+				fmt.Sprintf("CREATE INDEX %[1]s_ar_a_idx ON %[1]s_ar (ACTION_ID)%[2]s", table, indexStorage()),
+				fmt.Sprintf("CREATE INDEX %[1]s_rr_r_idx ON %[1]s_rr (RESOURCE_ID)%[2]s", table, indexStorage()),
+			},
+			Down: []string{
+				fmt.Sprintf("DROP INDEX %s_rr_r_idx", table),
+				fmt.Sprintf("DROP INDEX %s_ar_a_idx", table),
+				fmt.Sprintf("DROP INDEX %s_sr_s_idx", table),
+			},
+		},
+		{
+			Version: 4,
+			Up:      conditions,
+			Down:    blob,
+		},
+	}
+}
+
+// policyConditionsToBLOB returns a PL/SQL block copying the conditions of the CLOB column to CONDITIONS_NEW as UTF-8
+// encoded BLOB.
+func policyConditionsToBLOB(table string) string {
+	return fmt.Sprintf(`DECLARE
+	data BLOB;
+	dest_offset INTEGER;
+	src_offset INTEGER;
+	lang_context INTEGER;
+	warning INTEGER;
+BEGIN
+	FOR r IN (SELECT ROWID AS rid, CONDITIONS FROM %[1]s_p WHERE CONDITIONS IS NOT NULL) LOOP
+		DBMS_LOB.CREATETEMPORARY(data, TRUE);
+		IF DBMS_LOB.GETLENGTH(r.CONDITIONS) > 0 THEN
+			dest_offset := 1;
+			src_offset := 1;
+			lang_context := DBMS_LOB.DEFAULT_LANG_CTX;
+			DBMS_LOB.CONVERTTOBLOB(data, r.CONDITIONS, DBMS_LOB.LOBMAXSIZE, dest_offset, src_offset, NLS_CHARSET_ID('AL32UTF8'), lang_context, warning);
+		END IF;
+		UPDATE %[1]s_p SET CONDITIONS_NEW = data WHERE ROWID = r.rid;
+		DBMS_LOB.FREETEMPORARY(data);
+	END LOOP;
+END;`, table)
+}
+
+// policyConditionsToLongRaw returns a PL/SQL block copying the conditions of the BLOB column to CONDITIONS_NEW as
+// LONG RAW. PL/SQL can not bind more than 32760 bytes to a LONG RAW, so larger conditions fail the conversion.
+func policyConditionsToLongRaw(table string) string {
+	return fmt.Sprintf(`DECLARE
+	data RAW(32767);
+BEGIN
+	FOR r IN (SELECT ROWID AS rid, ID, CONDITIONS FROM %[1]s_p WHERE CONDITIONS IS NOT NULL) LOOP
+		IF DBMS_LOB.GETLENGTH(r.CONDITIONS) > 32760 THEN
+			RAISE_APPLICATION_ERROR(-20003, 'Conditions of policy ' || r.ID || ' exceed 32760 bytes and can not be stored as LONG RAW');
+		END IF;
+		data := DBMS_LOB.SUBSTR(r.CONDITIONS, 32760, 1);
+		UPDATE %[1]s_p SET CONDITIONS_NEW = data WHERE ROWID = r.rid;
+	END LOOP;
+END;`, table)
+}
+
+var policyTables = func(table string) []TableDefinition {
+	tables := []TableDefinition{
+		{
+			Name: table + "_p",
+			Columns: []ColumnDefinition{
+				{"ID", "VARCHAR2", 255, false},
+				{"DESCRIPTION", "VARCHAR2", 4000, true},
+				{"EFFECT", "VARCHAR2", 4000, false},
+				{"CONDITIONS", policyConditionsType(), 0, true},
+			},
+			PrimaryKey: []string{"ID"},
+		},
+	}
+
+	// subject, action and resource templates
+	for _, kind := range []string{"s", "a", "r"} {
+		tables = append(tables, TableDefinition{
+			Name: table + "_" + kind,
+			Columns: []ColumnDefinition{
+				{"ID", "VARCHAR2", 64, false},
+				{"HAS_REGEX", "CHAR", 1, false},
+				{"COMPILED", "VARCHAR2", 511, false},
+				{"TEMPLATE", "VARCHAR2", 511, false},
+			},
+			PrimaryKey: []string{"ID"},
+			Indexes:    [][]string{{"COMPILED"}, {"TEMPLATE"}},
+		})
+	}
+
+	// relations of templates to policies
+	for _, r := range []struct{ t, c string }{{t: "s", c: "SUBJECT"}, {t: "a", c: "ACTION_ID"}, {t: "r", c: "RESOURCE_ID"}} {
+		tables = append(tables, TableDefinition{
+			Name: table + "_" + r.t + "r",
+			Columns: []ColumnDefinition{
+				{"POLICY", "VARCHAR2", 255, false},
+				{r.c, "VARCHAR2", 64, false},
+			},
+			PrimaryKey: []string{"POLICY", r.c},
+			ForeignKeys: []ForeignKeyDefinition{
+				{[]string{"POLICY"}, table + "_p"},
+				{[]string{r.c}, table + "_" + r.t},
+			},
+			Indexes: [][]string{{r.c}},
+		})
+	}
+	return tables
+}
+
+// policyConditionsType returns the data type of the CONDITIONS column as configured.
+func policyConditionsType() string {
+	if strings.ToUpper(viper.GetString(configPolicyConditions)) == policyConditionsJSON {
+		return "CLOB"
+	}
+	return "BLOB"
+}
+
+// verifyConditions fails if POLICY_CONDITIONS differs from the type migration 4 converted the conditions to. The
+// migration is not applied again when the setting changes, so policies would be bound with the wrong type otherwise.
+// Schemas which have not been migrated to version 4 yet are converted as configured when they are.
+func (s *PolicyManager) verifyConditions() error {
+	applied, err := (&Migrator{DB: s.DB}).Applied(s.MigrationSet())
+	if err != nil {
+		return err
+	} else if _, ok := applied[4]; !ok {
+		return nil
+	}
+
+	var dataType string
+	query := "SELECT DATA_TYPE FROM ALL_TAB_COLUMNS WHERE OWNER = " + currentSchemaOwner + " AND TABLE_NAME = UPPER(?) AND COLUMN_NAME = 'CONDITIONS'"
+	if err := s.DB.Get(&dataType, s.DB.Rebind(query), s.GetTable()+"_p"); err != nil {
+		return errors.Wrapf(err, "Could not read the type of the conditions of %s_p", s.GetTable())
+	} else if dataType != policyConditionsType() {
+		return errors.Errorf(
+			"%s requires the conditions of %s_p to be stored as %s, but they are stored as %s. Set %s as before, or revert migration 4 of the policy manager with migrate to 3 using the previous setting and apply it again with migrate up",
+			configPolicyConditions, s.GetTable(), policyConditionsType(), dataType, configPolicyConditions,
+		)
+	}
+	return nil
+}
+
 // PolicyManager is a postgres implementation for Manager to store policies persistently.
 type PolicyManager struct {
 	DB    *sqlx.DB
@@ -84,34 +269,32 @@ type PolicyManager struct {
 
 func (s *PolicyManager) GetTable() string {
 	if s.Table == "" {
-		return "hydpol"
+		return tableName(configTablePolicy)
 	}
 	return s.Table
 }
 
-func (s *PolicyManager) CreateSchemas() (int, error) {
-	tx, err := s.DB.Begin()
-	if err != nil {
-		return 0, errors.WithStack(err)
-	}
-
-	for _, schema := range policySchemas(s.GetTable()) {
-		if _, err := tx.Exec(schema); err != nil {
-			if err := tx.Rollback(); err != nil {
-				return 0, errors.WithStack(err)
-			}
-			return 0, errors.Wrapf(err, "Could not migrate policy sql schema: %s", schema)
-		}
+func (s *PolicyManager) MigrationSet() *MigrationSet {
+	return &MigrationSet{
+		Manager:    "policy",
+		Table:      s.GetTable(),
+		Migrations: policyMigrations(s.GetTable()),
+		Tables:     policyTables(s.GetTable()),
+		Lookups: []Lookup{
+			{Name: "find request candidates", Query: policyCandidatesQuery(s.GetTable())},
+			{Name: "find policies of a subject template", Query: policyTemplateQuery(s.GetTable(), "s", "SUBJECT"), Index: s.GetTable() + "_sr_s_idx"},
+			{Name: "find policies of an action template", Query: policyTemplateQuery(s.GetTable(), "a", "ACTION_ID"), Index: s.GetTable() + "_ar_a_idx"},
+			{Name: "find policies of a resource template", Query: policyTemplateQuery(s.GetTable(), "r", "RESOURCE_ID"), Index: s.GetTable() + "_rr_r_idx"},
+		},
 	}
+}
 
-	if err := tx.Commit(); err != nil {
-		if err := tx.Rollback(); err != nil {
-			return 0, errors.WithStack(err)
-		}
-		return 0, errors.WithStack(err)
+func (s *PolicyManager) CreateSchemas() (int, error) {
+	n, err := (&Migrator{DB: s.DB}).Up(s.MigrationSet())
+	if err != nil {
+		return n, errors.Wrap(err, "Could not migrate policy sql schema")
 	}
-
-	return 1, nil
+	return n, nil
 }
 
 // Create inserts a new policy
@@ -125,17 +308,20 @@ func (s *PolicyManager) Create(policy Policy) (err error) {
 		}
 	}
 
-	tx, err := s.DB.Begin()
+	ctx, cancel := queryContext(context.Background())
+	defer cancel()
+
+	tx, err := s.DB.BeginTx(ctx, nil)
 	if err != nil {
-		return errors.WithStack(err)
+		return classifyError(err)
 	}
 
 	query := fmt.Sprintf("INSERT /*+ IGNORE_ROW_ON_DUPKEY_INDEX (%[1]s_p, %[1]s_p_pk_idx ) */ INTO %[1]s_p (ID, DESCRIPTION, EFFECT, CONDITIONS) VALUES (?, ?, ?, ?)", s.GetTable())
-	if _, err = tx.Exec(s.DB.Rebind(query), policy.GetID(), policy.GetDescription(), policy.GetEffect(), conditions); err != nil {
+	if _, err = tx.ExecContext(ctx, s.DB.Rebind(query), policy.GetID(), policy.GetDescription(), policy.GetEffect(), policyConditionsValue(conditions)); err != nil {
 		if err := tx.Rollback(); err != nil {
 			return errors.WithStack(err)
 		}
-		return errors.WithStack(err)
+		return classifyError(err)
 	}
 
 	type relation struct {
@@ -159,18 +345,18 @@ func (s *PolicyManager) Create(policy Policy) (err error) {
 				return errors.WithStack(err)
 			}
 
-			if _, err := tx.Exec(s.DB.Rebind(fmt.Sprintf("INSERT /*+ IGNORE_ROW_ON_DUPKEY_INDEX (%[1]s_%[2]s, %[1]s_%[2]s_pk_idx) */ INTO %[1]s_%[2]s (ID, TEMPLATE, COMPILED, HAS_REGEX) VALUES (?, ?, ?, ?)", s.GetTable(), v.t)), id, template, compiled.String(), strings.Index(template, string(policy.GetStartDelimiter())) > -1); err != nil {
+			if _, err := tx.ExecContext(ctx, s.DB.Rebind(fmt.Sprintf("INSERT /*+ IGNORE_ROW_ON_DUPKEY_INDEX (%[1]s_%[2]s, %[1]s_%[2]s_pk_idx) */ INTO %[1]s_%[2]s (ID, TEMPLATE, COMPILED, HAS_REGEX) VALUES (?, ?, ?, ?)", s.GetTable(), v.t)), id, template, compiled.String(), strings.Index(template, string(policy.GetStartDelimiter())) > -1); err != nil {
 				if err := tx.Rollback(); err != nil {
 					return errors.WithStack(err)
 				}
-				return errors.WithStack(err)
+				return classifyError(err)
 			}
 
-			if _, err := tx.Exec(s.DB.Rebind(fmt.Sprintf("INSERT /*+ IGNORE_ROW_ON_DUPKEY_INDEX (%[1]s_%[2]sr, %[1]s_%[2]sr_pk_idx) */ INTO %[1]s_%[2]sr (POLICY, %[3]s) VALUES (?, ?)", s.GetTable(), v.t, v.c)), policy.GetID(), id); err != nil {
+			if _, err := tx.ExecContext(ctx, s.DB.Rebind(fmt.Sprintf("INSERT /*+ IGNORE_ROW_ON_DUPKEY_INDEX (%[1]s_%[2]sr, %[1]s_%[2]sr_pk_idx) */ INTO %[1]s_%[2]sr (POLICY, %[3]s) VALUES (?, ?)", s.GetTable(), v.t, v.c)), policy.GetID(), id); err != nil {
 				if err := tx.Rollback(); err != nil {
 					return errors.WithStack(err)
 				}
-				return errors.WithStack(err)
+				return classifyError(err)
 			}
 		}
 	}
@@ -179,14 +365,28 @@ func (s *PolicyManager) Create(policy Policy) (err error) {
 		if err := tx.Rollback(); err != nil {
 			return errors.WithStack(err)
 		}
-		return errors.WithStack(err)
+		return classifyError(err)
 	}
 
 	return nil
 }
 
-func (s *PolicyManager) FindRequestCandidates(r *Request) (Policies, error) {
-	var query string = fmt.Sprintf(`SELECT
+// policyConditionsValue binds conditions as text to CLOBs, as binary values would be converted to hexadecimal text.
+func policyConditionsValue(conditions []byte) interface{} {
+	if policyConditionsType() == "CLOB" {
+		return clob(conditions)
+	}
+	return blob(conditions)
+}
+
+// policyTemplateQuery finds the policies of a template, as done when a template is deleted by the cascading foreign
+// keys of the relations of templates to policies.
+var policyTemplateQuery = func(table, kind, column string) string {
+	return fmt.Sprintf("SELECT POLICY FROM %s_%sr WHERE %s = ?", table, kind, column)
+}
+
+var policyCandidatesQuery = func(table string) string {
+	return fmt.Sprintf(`SELECT
 	p.ID as ID, p.EFFECT as EFFECT, p.CONDITIONS as CONDITIONS, p.DESCRIPTION as DESCRIPTION, tsubject.TEMPLATE as "SUBJECT", tresource.TEMPLATE as "RESOURCE", taction.TEMPLATE as "ACTION"
 FROM
 	%[1]s_p p
@@ -204,17 +404,30 @@ WHERE
 ( tsubject.HAS_REGEX = 0 AND tsubject.TEMPLATE = ? )
 OR
 ( tsubject.HAS_REGEX = 1 AND REGEXP_LIKE (?, tsubject.COMPILED) )
-`, s.GetTable())
+`, table)
+}
+
+func (s *PolicyManager) FindRequestCandidates(r *Request) (Policies, error) {
+	ctx, cancel := queryContext(context.Background())
+	defer cancel()
 
-	rows, err := s.DB.Query(s.DB.Rebind(query), r.Subject, r.Subject)
-	if err == sql.ErrNoRows {
+	var policies Policies
+	if err := retryTransient(ctx, s.DB, func(conn *dbConn) error {
+		rows, err := conn.QueryContext(ctx, s.DB.Rebind(policyCandidatesQuery(s.GetTable())), r.Subject, r.Subject)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		policies, err = scanRows(rows)
+		return err
+	}); err == sql.ErrNoRows {
 		return nil, NewErrResourceNotFound(err)
 	} else if err != nil {
-		return nil, errors.WithStack(err)
+		return nil, classifyError(err)
 	}
-	defer rows.Close()
 
-	return scanRows(rows)
+	return policies, nil
 }
 
 func scanRows(rows *sql.Rows) (Policies, error) {
@@ -222,7 +435,7 @@ func scanRows(rows *sql.Rows) (Policies, error) {
 
 	for rows.Next() {
 		var p DefaultPolicy
-		var conditions []byte
+		var conditions blob
 		var resource, subject, action sql.NullString
 		p.Actions = []string{}
 		p.Subjects = []string{}
@@ -235,8 +448,10 @@ func scanRows(rows *sql.Rows) (Policies, error) {
 		}
 
 		p.Conditions = Conditions{}
-		if err := json.Unmarshal(conditions, &p.Conditions); err != nil {
-			return nil, errors.WithStack(err)
+		if len(conditions) > 0 {
+			if err := json.Unmarshal(conditions, &p.Conditions); err != nil {
+				return nil, errors.WithStack(err)
+			}
 		}
 
 		if c, ok := policies[p.ID]; ok {
@@ -301,17 +516,22 @@ var policyGetAllQuery = func(table string) string {
 
 // GetAll returns all policies
 func (s *PolicyManager) GetAll(limit, offset int64) (Policies, error) {
-	query := s.DB.Rebind(policyGetAllQuery(s.GetTable()))
+	ctx, cancel := queryContext(context.Background())
+	defer cancel()
 
-	rows, err := s.DB.Query(query)
-	if err != nil {
-		return nil, errors.WithStack(err)
-	}
-	defer rows.Close()
+	var pols Policies
+	query := s.DB.Rebind(policyGetAllQuery(s.GetTable()))
+	if err := retryTransient(ctx, s.DB, func(conn *dbConn) error {
+		rows, err := conn.QueryContext(ctx, query)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
 
-	pols, err := scanRows(rows)
-	if err != nil {
-		return nil, errors.WithStack(err)
+		pols, err = scanRows(rows)
+		return err
+	}); err != nil {
+		return nil, classifyError(err)
 	}
 
 	if offset + limit > int64(len(pols)) {
@@ -324,19 +544,24 @@ func (s *PolicyManager) GetAll(limit, offset int64) (Policies, error) {
 
 // Get retrieves a policy.
 func (s *PolicyManager) Get(id string) (Policy, error) {
+	ctx, cancel := queryContext(context.Background())
+	defer cancel()
+
+	var policies Policies
 	query := s.DB.Rebind(policyGetAllQuery(s.GetTable()) + "WHERE p.ID=?")
+	if err := retryTransient(ctx, s.DB, func(conn *dbConn) error {
+		rows, err := conn.QueryContext(ctx, query, id)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
 
-	rows, err := s.DB.Query(query, id)
-	if err == sql.ErrNoRows {
+		policies, err = scanRows(rows)
+		return err
+	}); err == sql.ErrNoRows {
 		return nil, NewErrResourceNotFound(err)
 	} else if err != nil {
-		return nil, errors.WithStack(err)
-	}
-	defer rows.Close()
-
-	policies, err := scanRows(rows)
-	if err != nil {
-		return nil, errors.WithStack(err)
+		return nil, classifyError(err)
 	} else if len(policies) == 0 {
 		return nil, NewErrResourceNotFound(sql.ErrNoRows)
 	}
@@ -346,9 +571,12 @@ func (s *PolicyManager) Get(id string) (Policy, error) {
 
 // Delete removes a policy.
 func (s *PolicyManager) Delete(id string) error {
+	ctx, cancel := queryContext(context.Background())
+	defer cancel()
+
 	query := fmt.Sprintf("DELETE FROM %s_p WHERE ID=?", s.GetTable())
-	_, err := s.DB.Exec(s.DB.Rebind(query), id)
-	return errors.WithStack(err)
+	_, err := s.DB.ExecContext(ctx, s.DB.Rebind(query), id)
+	return classifyError(err)
 }
 
 func uniq(input []string) []string {
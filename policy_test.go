@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/ory/ladon"
+	"github.com/spf13/viper"
 )
 
 var policyManager *PolicyManager
@@ -31,3 +32,57 @@ func TestFindPoliciesForSubject(t *testing.T) {
 func TestCreateGetDelete(t *testing.T) {
 	ladon.TestHelperCreateGetDelete(policyManager)(t)
 }
+
+func TestPolicyMigrationsRevertConditions(t *testing.T) {
+	db := connect(os.Getenv("ORACLE_DSN"))
+	m := &PolicyManager{DB: db, Table: randomTableName("pol")}
+	if _, err := m.CreateSchemas(); err != nil {
+		t.Fatalf("Could not create policy schema: %s", err)
+	}
+
+	policy := &ladon.DefaultPolicy{
+		ID:         "conditions",
+		Subjects:   []string{"peter"},
+		Resources:  []string{"articles:1"},
+		Actions:    []string{"read"},
+		Effect:     ladon.AllowAccess,
+		Conditions: ladon.Conditions{"owner": &ladon.StringEqualCondition{Equals: "peter"}},
+	}
+	if err := m.Create(policy); err != nil {
+		t.Fatalf("Could not create policy: %s", err)
+	}
+
+	// Reverting the second migration restores the LONG RAW conditions, which are converted again on migrating up.
+	migrator := &Migrator{DB: db}
+	if _, _, err := migrator.To(m.MigrationSet(), 1); err != nil {
+		t.Fatalf("Could not revert policy migrations: %s", err)
+	}
+	if _, err := m.CreateSchemas(); err != nil {
+		t.Fatalf("Could not apply reverted policy migrations: %s", err)
+	}
+
+	got, err := m.Get(policy.ID)
+	if err != nil {
+		t.Fatalf("Could not get policy: %s", err)
+	}
+	condition, ok := got.GetConditions()["owner"].(*ladon.StringEqualCondition)
+	if !ok || condition.Equals != "peter" {
+		t.Fatalf("Expected conditions to survive reverting the migrations but got %v", got.GetConditions())
+	}
+
+	if _, _, err := migrator.To(m.MigrationSet(), 0); err != nil {
+		t.Fatalf("Could not revert all policy migrations: %s", err)
+	}
+}
+
+func TestVerifyConditionsRefusesChangedSetting(t *testing.T) {
+	if err := policyManager.verifyConditions(); err != nil {
+		t.Fatalf("Expected the conditions to match %s but got %s", configPolicyConditions, err)
+	}
+
+	viper.Set(configPolicyConditions, policyConditionsJSON)
+	defer viper.Set(configPolicyConditions, policyConditionsBLOB)
+	if err := policyManager.verifyConditions(); err == nil {
+		t.Fatalf("Expected changing %s after migration 4 to be refused", configPolicyConditions)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"database/sql"
+	"expvar"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/spf13/viper"
+)
+
+var (
+	pools     = map[string]*sqlx.DB{}
+	poolsLock sync.RWMutex
+)
+
+func init() {
+	// Published on /debug/vars of the default HTTP server, which the root command serves on :4040.
+	expvar.Publish("oracle_pools", expvar.Func(poolStats))
+}
+
+// poolSettings are the limits of a connection pool. Zero values keep the defaults of database/sql: unlimited open
+// connections, two idle connections and no maximum lifetime or idle time.
+type poolSettings struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+// poolSettingsOf returns the pool limits set in the DSN or else in the POOL_* config.
+func poolSettingsOf(dsn *DSN) poolSettings {
+	settings := poolSettings{
+		MaxOpenConns:    dsn.MaxOpenConns,
+		MaxIdleConns:    dsn.MaxIdleConns,
+		ConnMaxLifetime: dsn.ConnMaxLifetime,
+		ConnMaxIdleTime: dsn.ConnMaxIdleTime,
+	}
+	if settings.MaxOpenConns == 0 {
+		settings.MaxOpenConns = viper.GetInt(configPoolMaxOpenConns)
+	}
+	if settings.MaxIdleConns == 0 {
+		settings.MaxIdleConns = viper.GetInt(configPoolMaxIdleConns)
+	}
+	if settings.ConnMaxLifetime == 0 {
+		settings.ConnMaxLifetime = viper.GetDuration(configPoolConnMaxLifetime)
+	}
+	if settings.ConnMaxIdleTime == 0 {
+		settings.ConnMaxIdleTime = viper.GetDuration(configPoolConnMaxIdleTime)
+	}
+	return settings
+}
+
+// configurePool limits the connection pool as set in the DSN or else in the POOL_* config.
+func configurePool(db *sqlx.DB, dsn *DSN) {
+	settings := poolSettingsOf(dsn)
+	db.SetMaxOpenConns(settings.MaxOpenConns)
+	if settings.MaxIdleConns != 0 {
+		db.SetMaxIdleConns(settings.MaxIdleConns)
+	}
+	db.SetConnMaxLifetime(settings.ConnMaxLifetime)
+	setConnMaxIdleTime(db.DB, settings)
+}
+
+// registerPoolStats reports the statistics of the pool by the redacted DSN. Registering a pool of the same DSN
+// again replaces the former pool.
+func registerPoolStats(name string, db *sqlx.DB) {
+	poolsLock.Lock()
+	defer poolsLock.Unlock()
+	pools[name] = db
+}
+
+func poolStats() interface{} {
+	poolsLock.RLock()
+	defer poolsLock.RUnlock()
+
+	stats := map[string]sql.DBStats{}
+	for name, db := range pools {
+		stats[name] = db.Stats()
+	}
+	return stats
+}
@@ -0,0 +1,12 @@
+//go:build go1.15
+// +build go1.15
+
+package main
+
+import "database/sql"
+
+// setConnMaxIdleTime closes connections which have been idle for longer than the idle time of the settings, e.g.
+// before a firewall drops them silently.
+func setConnMaxIdleTime(db *sql.DB, settings poolSettings) {
+	db.SetConnMaxIdleTime(settings.ConnMaxIdleTime)
+}
@@ -0,0 +1,15 @@
+//go:build !go1.15
+// +build !go1.15
+
+package main
+
+import "database/sql"
+
+// setConnMaxIdleTime limits the lifetime of connections to the idle time of the settings, as database/sql can not
+// close idle connections before Go 1.15. Connections are then replaced after the idle time even if they are in use,
+// which still keeps idle connections below a firewall's cutoff.
+func setConnMaxIdleTime(db *sql.DB, settings poolSettings) {
+	if idle := settings.ConnMaxIdleTime; idle != 0 && (settings.ConnMaxLifetime == 0 || idle < settings.ConnMaxLifetime) {
+		db.SetConnMaxLifetime(idle)
+	}
+}
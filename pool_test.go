@@ -0,0 +1,59 @@
+package main
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/spf13/viper"
+)
+
+func TestConfigurePool(t *testing.T) {
+	for _, key := range []string{configPoolMaxOpenConns, configPoolMaxIdleConns, configPoolConnMaxLifetime, configPoolConnMaxIdleTime} {
+		defer viper.Set(key, nil)
+	}
+	viper.Set(configPoolMaxOpenConns, 10)
+	viper.Set(configPoolMaxIdleConns, 4)
+	viper.Set(configPoolConnMaxLifetime, "30m")
+	viper.Set(configPoolConnMaxIdleTime, "5m")
+
+	for k, c := range []struct {
+		dsn      DSN
+		expected poolSettings
+	}{
+		{
+			dsn:      DSN{},
+			expected: poolSettings{MaxOpenConns: 10, MaxIdleConns: 4, ConnMaxLifetime: 30 * time.Minute, ConnMaxIdleTime: 5 * time.Minute},
+		},
+		{
+			dsn:      DSN{MaxOpenConns: 5, MaxIdleConns: 1, ConnMaxLifetime: time.Hour, ConnMaxIdleTime: time.Minute},
+			expected: poolSettings{MaxOpenConns: 5, MaxIdleConns: 1, ConnMaxLifetime: time.Hour, ConnMaxIdleTime: time.Minute},
+		},
+		{
+			dsn:      DSN{MaxIdleConns: 2},
+			expected: poolSettings{MaxOpenConns: 10, MaxIdleConns: 2, ConnMaxLifetime: 30 * time.Minute, ConnMaxIdleTime: 5 * time.Minute},
+		},
+		{
+			dsn:      DSN{ConnMaxIdleTime: 10 * time.Minute},
+			expected: poolSettings{MaxOpenConns: 10, MaxIdleConns: 4, ConnMaxLifetime: 30 * time.Minute, ConnMaxIdleTime: 10 * time.Minute},
+		},
+	} {
+		if settings := poolSettingsOf(&c.dsn); settings != c.expected {
+			t.Errorf("Case %d: expected %+v but got %+v", k, c.expected, settings)
+		}
+	}
+
+	// The pool is not connected, so no session is opened.
+	db, err := sqlx.Open(sessionDriverName, "")
+	if err != nil {
+		t.Fatalf("Could not open pool: %s", err)
+	}
+	defer db.Close()
+
+	configurePool(db, &DSN{})
+	registerPoolStats("test", db)
+	if _, ok := poolStats().(map[string]sql.DBStats)["test"]; !ok {
+		t.Fatal("Expected the stats of the registered pool to be reported")
+	}
+}
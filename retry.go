@@ -0,0 +1,65 @@
+package main
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// backoff retries an operation with exponentially growing intervals until it succeeds or the next attempt would
+// start after the deadline. Each interval is randomized between half and the full interval, so that processes
+// started together do not retry in lockstep.
+type backoff struct {
+	Timeout         time.Duration
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+	L               logrus.FieldLogger
+}
+
+// connectBackoff returns the backoff of connecting to the database as configured.
+func connectBackoff() *backoff {
+	return &backoff{
+		Timeout:         viper.GetDuration(configConnectRetryTimeout),
+		InitialInterval: viper.GetDuration(configConnectRetryInitialInterval),
+		MaxInterval:     viper.GetDuration(configConnectRetryMaxInterval),
+		L:               logrus.StandardLogger(),
+	}
+}
+
+// Retry runs f until it succeeds and returns the last error of f if it does not succeed in time. A timeout of
+// zero runs f once.
+func (b *backoff) Retry(operation string, f func() error) error {
+	deadline := time.Now().Add(b.Timeout)
+	interval := b.InitialInterval
+	if interval < time.Millisecond {
+		interval = time.Millisecond
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := f()
+		if err == nil {
+			if attempt > 1 {
+				b.L.Infof("Was able to %s after %d attempts", operation, attempt)
+			}
+			return nil
+		}
+
+		wait := interval/2 + time.Duration(rand.Int63n(int64(interval/2)+1))
+		if time.Now().Add(wait).After(deadline) {
+			if attempt > 1 {
+				return errors.Wrapf(err, "Gave up after %d attempts", attempt)
+			}
+			return err
+		}
+
+		b.L.WithError(err).Warnf("Could not %s in attempt %d, retrying in %s", operation, attempt, wait)
+		time.Sleep(wait)
+
+		if interval *= 2; b.MaxInterval > 0 && interval > b.MaxInterval {
+			interval = b.MaxInterval
+		}
+	}
+}
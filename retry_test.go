@@ -0,0 +1,40 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+)
+
+func TestBackoffRetry(t *testing.T) {
+	b := &backoff{Timeout: time.Second, InitialInterval: time.Millisecond, MaxInterval: 4 * time.Millisecond, L: logrus.New()}
+
+	var attempts int
+	if err := b.Retry("succeed", func() error {
+		if attempts++; attempts < 3 {
+			return errors.New("not yet")
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("Expected the third attempt to succeed but got %s", err)
+	} else if attempts != 3 {
+		t.Fatalf("Expected 3 attempts but got %d", attempts)
+	}
+
+	b.Timeout = 20 * time.Millisecond
+	start := time.Now()
+	if err := b.Retry("fail", func() error { return errors.New("never") }); err == nil {
+		t.Fatal("Expected retrying to give up")
+	} else if time.Since(start) > time.Second {
+		t.Fatalf("Expected retrying to give up at the deadline but it took %s", time.Since(start))
+	}
+
+	attempts = 0
+	b.Timeout = 0
+	b.Retry("fail once", func() error { attempts++; return errors.New("never") })
+	if attempts != 1 {
+		t.Fatalf("Expected a single attempt without timeout but got %d", attempts)
+	}
+}
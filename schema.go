@@ -0,0 +1,276 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// TableDefinition describes a table as the code of a manager expects it after all migrations have been applied.
+// The index of the primary key is implied, Indexes lists all other indexes by their columns.
+type TableDefinition struct {
+	Name        string
+	Columns     []ColumnDefinition
+	PrimaryKey  []string
+	ForeignKeys []ForeignKeyDefinition
+	Indexes     [][]string
+}
+
+// ColumnDefinition describes a column. Type is the data type as reported by ALL_TAB_COLUMNS. Length is the length
+// in characters and is only compared for character types.
+type ColumnDefinition struct {
+	Name     string
+	Type     string
+	Length   int
+	Nullable bool
+}
+
+// ForeignKeyDefinition describes a foreign key by its columns and the referenced table.
+type ForeignKeyDefinition struct {
+	Columns    []string
+	References string
+}
+
+// SchemaDifference is a single deviation of the live schema from a table definition.
+type SchemaDifference struct {
+	Table    string `json:"table"`
+	Object   string `json:"object"`
+	Name     string `json:"name"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+}
+
+type liveColumn struct {
+	Name     string `db:"COLUMN_NAME"`
+	Type     string `db:"DATA_TYPE"`
+	Length   int    `db:"CHAR_LENGTH"`
+	Nullable string `db:"NULLABLE"`
+}
+
+type liveConstraintColumn struct {
+	Constraint string `db:"CONSTRAINT_NAME"`
+	Column     string `db:"COLUMN_NAME"`
+	References string `db:"R_TABLE_NAME"`
+}
+
+type liveIndexColumn struct {
+	Index  string `db:"INDEX_NAME"`
+	Column string `db:"COLUMN_NAME"`
+}
+
+const (
+	liveColumnsQuery = `SELECT COLUMN_NAME, DATA_TYPE, CHAR_LENGTH, NULLABLE FROM ALL_TAB_COLUMNS
+WHERE OWNER = ` + currentSchemaOwner + ` AND TABLE_NAME = UPPER(?) ORDER BY COLUMN_ID`
+	livePrimaryKeyQuery = `SELECT cc.COLUMN_NAME FROM ALL_CONSTRAINTS c
+INNER JOIN ALL_CONS_COLUMNS cc ON cc.OWNER = c.OWNER AND cc.CONSTRAINT_NAME = c.CONSTRAINT_NAME
+WHERE c.OWNER = ` + currentSchemaOwner + ` AND c.TABLE_NAME = UPPER(?) AND c.CONSTRAINT_TYPE = 'P' ORDER BY cc.POSITION`
+	liveForeignKeysQuery = `SELECT c.CONSTRAINT_NAME, cc.COLUMN_NAME, r.TABLE_NAME AS R_TABLE_NAME FROM ALL_CONSTRAINTS c
+INNER JOIN ALL_CONS_COLUMNS cc ON cc.OWNER = c.OWNER AND cc.CONSTRAINT_NAME = c.CONSTRAINT_NAME
+INNER JOIN ALL_CONSTRAINTS r ON r.OWNER = c.R_OWNER AND r.CONSTRAINT_NAME = c.R_CONSTRAINT_NAME
+WHERE c.OWNER = ` + currentSchemaOwner + ` AND c.TABLE_NAME = UPPER(?) AND c.CONSTRAINT_TYPE = 'R' ORDER BY c.CONSTRAINT_NAME, cc.POSITION`
+	liveIndexesQuery = `SELECT INDEX_NAME, COLUMN_NAME FROM ALL_IND_COLUMNS
+WHERE TABLE_OWNER = ` + currentSchemaOwner + ` AND TABLE_NAME = UPPER(?) ORDER BY INDEX_NAME, COLUMN_POSITION`
+)
+
+// VerifySchema compares the live schema with the given table definitions and returns all differences.
+func VerifySchema(db *sqlx.DB, tables []TableDefinition) ([]SchemaDifference, error) {
+	var diff []SchemaDifference
+	for _, table := range tables {
+		d, err := verifyTable(db, table)
+		if err != nil {
+			return nil, err
+		}
+		diff = append(diff, d...)
+	}
+	return diff, nil
+}
+
+func verifyTable(db *sqlx.DB, table TableDefinition) ([]SchemaDifference, error) {
+	name := strings.ToUpper(table.Name)
+	difference := func(object, n, expected, actual string) SchemaDifference {
+		return SchemaDifference{Table: name, Object: object, Name: n, Expected: expected, Actual: actual}
+	}
+
+	var columns []liveColumn
+	if err := db.Select(&columns, db.Rebind(liveColumnsQuery), table.Name); err != nil {
+		return nil, errors.WithStack(err)
+	} else if len(columns) == 0 {
+		return []SchemaDifference{difference("table", name, "exists", "missing")}, nil
+	}
+
+	var diff []SchemaDifference
+	live := map[string]liveColumn{}
+	for _, c := range columns {
+		live[c.Name] = c
+	}
+
+	for _, expected := range table.Columns {
+		column := strings.ToUpper(expected.Name)
+		actual, ok := live[column]
+		if !ok {
+			diff = append(diff, difference("column", column, expected.String(), "missing"))
+			continue
+		}
+		delete(live, column)
+
+		if !expected.matches(actual) {
+			diff = append(diff, difference("column", column, expected.String(), actual.String()))
+		}
+	}
+
+	for _, c := range columns {
+		if _, ok := live[c.Name]; ok {
+			diff = append(diff, difference("column", c.Name, "missing", c.String()))
+		}
+	}
+
+	var pk []string
+	if err := db.Select(&pk, db.Rebind(livePrimaryKeyQuery), table.Name); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if expected, actual := columnList(table.PrimaryKey), columnList(pk); expected != actual {
+		diff = append(diff, difference("primary key", "", expected, actual))
+	}
+
+	var fks []liveConstraintColumn
+	if err := db.Select(&fks, db.Rebind(liveForeignKeysQuery), table.Name); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	diff = append(diff, compareSets("foreign key", name, foreignKeyStrings(table.ForeignKeys), groupForeignKeys(fks))...)
+
+	var indexes []liveIndexColumn
+	if err := db.Select(&indexes, db.Rebind(liveIndexesQuery), table.Name); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	expectedIndexes := []string{columnList(table.PrimaryKey)}
+	for _, index := range table.Indexes {
+		expectedIndexes = append(expectedIndexes, columnList(index))
+	}
+	diff = append(diff, compareSets("index", name, expectedIndexes, groupIndexes(indexes))...)
+
+	return diff, nil
+}
+
+// compareSets reports expected entries missing in live and live entries which are not expected. live maps the
+// compared representation to the name of the live object.
+func compareSets(object, table string, expected []string, live map[string]string) []SchemaDifference {
+	var diff []SchemaDifference
+	seen := map[string]bool{}
+	for _, e := range expected {
+		seen[e] = true
+		if _, ok := live[e]; !ok {
+			diff = append(diff, SchemaDifference{Table: table, Object: object, Expected: e, Actual: "missing"})
+		}
+	}
+
+	var unexpected []string
+	for l := range live {
+		if !seen[l] {
+			unexpected = append(unexpected, l)
+		}
+	}
+	sort.Strings(unexpected)
+
+	for _, l := range unexpected {
+		diff = append(diff, SchemaDifference{Table: table, Object: object, Name: live[l], Expected: "missing", Actual: l})
+	}
+	return diff
+}
+
+func (c ColumnDefinition) String() string {
+	return columnString(c.Type, c.Length, c.Nullable)
+}
+
+func (c ColumnDefinition) matches(l liveColumn) bool {
+	if !strings.EqualFold(c.Type, l.Type) || c.Nullable != (l.Nullable == "Y") {
+		return false
+	}
+	return c.Length == 0 || c.Length == l.Length
+}
+
+func (l liveColumn) String() string {
+	length := l.Length
+	if !isCharacterType(l.Type) {
+		length = 0
+	}
+	return columnString(l.Type, length, l.Nullable == "Y")
+}
+
+func (f ForeignKeyDefinition) String() string {
+	return fmt.Sprintf("%s REFERENCES %s", columnList(f.Columns), strings.ToUpper(f.References))
+}
+
+func columnString(kind string, length int, nullable bool) string {
+	s := strings.ToUpper(kind)
+	if length > 0 {
+		s = fmt.Sprintf("%s(%d)", s, length)
+	}
+	if nullable {
+		return s + " NULL"
+	}
+	return s + " NOT NULL"
+}
+
+func isCharacterType(kind string) bool {
+	switch strings.ToUpper(kind) {
+	case "CHAR", "NCHAR", "VARCHAR2", "NVARCHAR2":
+		return true
+	}
+	return false
+}
+
+func columnList(columns []string) string {
+	upper := make([]string, len(columns))
+	for i, c := range columns {
+		upper[i] = strings.ToUpper(c)
+	}
+	return "(" + strings.Join(upper, ", ") + ")"
+}
+
+func foreignKeyStrings(fks []ForeignKeyDefinition) []string {
+	s := make([]string, len(fks))
+	for i, fk := range fks {
+		s[i] = fk.String()
+	}
+	return s
+}
+
+// groupForeignKeys maps the foreign keys given by their columns to their constraint names.
+func groupForeignKeys(rows []liveConstraintColumn) map[string]string {
+	var names []string
+	fks := map[string]*ForeignKeyDefinition{}
+	for _, row := range rows {
+		if _, ok := fks[row.Constraint]; !ok {
+			fks[row.Constraint] = &ForeignKeyDefinition{References: row.References}
+			names = append(names, row.Constraint)
+		}
+		fks[row.Constraint].Columns = append(fks[row.Constraint].Columns, row.Column)
+	}
+
+	grouped := map[string]string{}
+	for _, name := range names {
+		grouped[fks[name].String()] = name
+	}
+	return grouped
+}
+
+// groupIndexes maps the indexes given by their columns to their names.
+func groupIndexes(rows []liveIndexColumn) map[string]string {
+	var names []string
+	indexes := map[string][]string{}
+	for _, row := range rows {
+		if _, ok := indexes[row.Index]; !ok {
+			names = append(names, row.Index)
+		}
+		indexes[row.Index] = append(indexes[row.Index], row.Column)
+	}
+
+	grouped := map[string]string{}
+	for _, name := range names {
+		grouped[columnList(indexes[name])] = name
+	}
+	return grouped
+}
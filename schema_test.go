@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestTableDefinitionsCoverSqlParams(t *testing.T) {
+	for _, c := range []struct {
+		params []string
+		table  TableDefinition
+	}{
+		{params: clientSqlParams, table: clientTables("hyd_clt")[0]},
+		{params: sqlParams, table: fositeTables("hyd_oa2")[0]},
+	} {
+		for _, param := range c.params {
+			var found bool
+			for _, column := range c.table.Columns {
+				found = found || strings.EqualFold(column.Name, param)
+			}
+			if !found {
+				t.Errorf("Expected table definition %s to contain column %s", c.table.Name, param)
+			}
+		}
+	}
+}
+
+func TestVerifySchema(t *testing.T) {
+	db := connect(os.Getenv("ORACLE_DSN"))
+	m := &ClientManager{DB: db, Table: randomTableName("verify")}
+	if _, err := m.CreateSchemas(); err != nil {
+		t.Fatalf("Could not create client schema: %s", err)
+	}
+
+	diff, err := VerifySchema(db, m.MigrationSet().Tables)
+	if err != nil {
+		t.Fatalf("Could not verify schema: %s", err)
+	} else if len(diff) > 0 {
+		t.Fatalf("Expected no differences but got %v", diff)
+	}
+
+	if _, err := db.Exec(fmt.Sprintf("ALTER TABLE %s MODIFY (OWNER VARCHAR2 (255))", m.GetTable())); err != nil {
+		t.Fatalf("Could not alter table: %s", err)
+	}
+
+	diff, err = VerifySchema(db, m.MigrationSet().Tables)
+	if err != nil {
+		t.Fatalf("Could not verify schema: %s", err)
+	} else if len(diff) != 1 || diff[0].Name != "OWNER" {
+		t.Fatalf("Expected column OWNER to differ but got %v", diff)
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// selectSchema selects the current schema. The schema is bound rather than interpolated and checked by
+// DBMS_ASSERT, so that it can only ever be a simple SQL name.
+const selectSchema = `BEGIN
+	EXECUTE IMMEDIATE 'ALTER SESSION SET CURRENT_SCHEMA = ' || DBMS_ASSERT.SIMPLE_SQL_NAME(?);
+END;`
+
+// setModule tags the session with module and action, which are shown in V$SESSION and by monitoring tools.
+const setModule = `BEGIN
+	DBMS_APPLICATION_INFO.SET_MODULE(?, ?);
+END;`
+
+// sessionStatement is a statement configuring a session, with placeholders in the ? notation.
+type sessionStatement struct {
+	query string
+	args  []interface{}
+}
+
+// sessionStatements returns the statements run on every new connection: the schema of the DSN, the NLS settings
+// of the DSN or else of the config, the module and action tags and finally the statements of SESSION_STATEMENTS.
+// Settings which can not be bound are quoted as literals.
+func sessionStatements(d *DSN) []sessionStatement {
+	var statements []sessionStatement
+	if d.Schema != "" {
+		statements = append(statements, sessionStatement{query: selectSchema, args: []interface{}{d.Schema}})
+	}
+
+	for _, s := range []struct{ parameter, value, key string }{
+		{parameter: "NLS_LANGUAGE", value: d.NLSLanguage},
+		{parameter: "NLS_TERRITORY", value: d.NLSTerritory},
+		{parameter: "NLS_DATE_FORMAT", value: d.NLSDateFormat, key: configSessionNLSDateFormat},
+		{parameter: "NLS_TIMESTAMP_FORMAT", value: d.NLSTimestampFormat, key: configSessionNLSTimestampFormat},
+		{parameter: "TIME_ZONE", value: d.TimeZone, key: configSessionTimeZone},
+	} {
+		value := s.value
+		if value == "" && s.key != "" {
+			value = viper.GetString(s.key)
+		}
+		if value != "" {
+			statements = append(statements, sessionStatement{query: fmt.Sprintf("ALTER SESSION SET %s = %s", s.parameter, quoteLiteral(value))})
+		}
+	}
+
+	if module := viper.GetString(configSessionModule); module != "" {
+		statements = append(statements, sessionStatement{query: setModule, args: []interface{}{module, viper.GetString(configSessionAction)}})
+	}
+
+	for _, statement := range configStatements(configSessionStatements) {
+		statements = append(statements, sessionStatement{query: statement})
+	}
+	return statements
+}
+
+// configStatements returns the statements configured as list or, e.g. in environment variables, as a string
+// separated by semicolons.
+func configStatements(key string) []string {
+	value, ok := viper.Get(key).(string)
+	if !ok {
+		return viper.GetStringSlice(key)
+	}
+
+	var statements []string
+	for _, statement := range strings.Split(value, ";") {
+		if statement = strings.TrimSpace(statement); statement != "" {
+			statements = append(statements, statement)
+		}
+	}
+	return statements
+}
+
+// sessionDriverName is the name the session driver is registered with in database/sql.
+const sessionDriverName = "hydra-oracle-session"
+
+// sessionDriver opens connections of a driver and configures the session of each new connection, because
+// session settings such as the current schema are lost whenever the pool replaces a connection. database/sql
+// passes the data source name of a pool to Open, so the data source name of each pool is its encoded session and
+// the driver keeps no state per pool or connection. Only the drivers sessions are opened with are cached.
+type sessionDriver struct {
+	sync.RWMutex
+	drivers map[string]driver.Driver
+}
+
+// session is the data source name of the session driver.
+type session struct {
+	Driver     string             `json:"driver"`
+	DSN        string             `json:"dsn"`
+	Statements []sessionStatement `json:"statements"`
+}
+
+func (s sessionStatement) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Query string        `json:"query"`
+		Args  []interface{} `json:"args"`
+	}{Query: s.query, Args: s.args})
+}
+
+func (s *sessionStatement) UnmarshalJSON(data []byte) error {
+	var v struct {
+		Query string        `json:"query"`
+		Args  []interface{} `json:"args"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	s.query, s.args = v.Query, v.Args
+	return nil
+}
+
+var sessions = &sessionDriver{drivers: map[string]driver.Driver{}}
+
+func init() {
+	sql.Register(sessionDriverName, sessions)
+}
+
+// openSession opens a connection pool configuring each session with the given statements. Both ora and go-ora
+// bind placeholders in the :name notation, which sqlx knows by the driver name ora.
+func openSession(driverName, dsn string, statements []sessionStatement) (*sqlx.DB, error) {
+	// sql.Open does not connect, it is only used to look up the registered driver.
+	db, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	d := db.Driver()
+	db.Close()
+
+	sessions.Lock()
+	sessions.drivers[driverName] = d
+	sessions.Unlock()
+
+	key, err := json.Marshal(&session{Driver: driverName, DSN: dsn, Statements: statements})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	db, err = sql.Open(sessionDriverName, string(key))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return sqlx.NewDb(db, driverOra), nil
+}
+
+func (d *sessionDriver) Open(key string) (driver.Conn, error) {
+	var s session
+	if err := json.Unmarshal([]byte(key), &s); err != nil {
+		return nil, errors.Wrap(err, "Could not decode the session")
+	}
+
+	d.RLock()
+	opener, ok := d.drivers[s.Driver]
+	d.RUnlock()
+	if !ok {
+		return nil, errors.Errorf("Unknown driver %s of session", s.Driver)
+	}
+
+	conn, err := opener.Open(s.DSN)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, statement := range s.Statements {
+		if err := execConn(context.Background(), conn, sqlx.Rebind(sqlx.BindType(driverOra), statement.query), statement.args); err != nil {
+			conn.Close()
+			return nil, errors.Wrapf(err, "Could not configure session with: %s", statement.query)
+		}
+	}
+	return conn, nil
+}
+
+// execConn executes a statement on a connection of the driver, which is not managed by database/sql yet.
+func execConn(ctx context.Context, conn driver.Conn, query string, args []interface{}) error {
+	named := make([]driver.NamedValue, len(args))
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		v, err := driver.DefaultParameterConverter.ConvertValue(arg)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+		values[i] = v
+	}
+
+	if execer, ok := conn.(driver.ExecerContext); ok {
+		_, err := execer.ExecContext(ctx, query, named)
+		if err != driver.ErrSkip {
+			return err
+		}
+	}
+
+	stmt, err := conn.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	if s, ok := stmt.(driver.StmtExecContext); ok {
+		_, err = s.ExecContext(ctx, named)
+		return err
+	}
+	_, err = stmt.Exec(values)
+	return err
+}
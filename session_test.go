@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestSchemaIsSelectedOnEveryConnection(t *testing.T) {
+	dsn, err := ParseDSN(os.Getenv("ORACLE_DSN"))
+	if err != nil {
+		t.Fatalf("Could not parse DSN: %s", err)
+	} else if dsn.Schema == "" {
+		t.Skip("The DSN does not select a schema")
+	}
+
+	db := connect(os.Getenv("ORACLE_DSN"))
+	ctx := context.Background()
+
+	// Connections held at the same time are distinct sessions of the pool.
+	for i := 0; i < 3; i++ {
+		conn, err := db.DB.Conn(ctx)
+		if err != nil {
+			t.Fatalf("Could not open connection: %s", err)
+		}
+		defer conn.Close()
+
+		var schema string
+		if err := conn.QueryRowContext(ctx, "SELECT SYS_CONTEXT('USERENV', 'CURRENT_SCHEMA') FROM DUAL").Scan(&schema); err != nil {
+			t.Fatalf("Could not query current schema: %s", err)
+		} else if schema != strings.ToUpper(dsn.Schema) {
+			t.Fatalf("Expected connection %d to use schema %s but got %s", i, strings.ToUpper(dsn.Schema), schema)
+		}
+	}
+}
+
+func TestSessionStatements(t *testing.T) {
+	for _, key := range []string{configSessionTimeZone, configSessionAction, configSessionStatements} {
+		defer viper.Set(key, nil)
+	}
+	viper.Set(configSessionTimeZone, "UTC")
+	viper.Set(configSessionAction, "test")
+	viper.Set(configSessionStatements, "ALTER SESSION SET OPTIMIZER_MODE = FIRST_ROWS; ALTER SESSION ENABLE PARALLEL DML")
+
+	statements := sessionStatements(&DSN{Schema: "hydra", NLSDateFormat: "YYYY-MM-DD"})
+	var queries []string
+	for _, s := range statements {
+		queries = append(queries, s.query)
+	}
+
+	expected := []string{
+		selectSchema,
+		"ALTER SESSION SET NLS_DATE_FORMAT = 'YYYY-MM-DD'",
+		"ALTER SESSION SET TIME_ZONE = 'UTC'",
+		setModule,
+		"ALTER SESSION SET OPTIMIZER_MODE = FIRST_ROWS",
+		"ALTER SESSION ENABLE PARALLEL DML",
+	}
+	if strings.Join(queries, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("Expected session statements %v but got %v", expected, queries)
+	}
+
+	if args := statements[3].args; args[0] != "hydra" || args[1] != "test" {
+		t.Fatalf("Expected module hydra and action test but got %v", args)
+	}
+}
+
+func TestSessionIsEncodedInTheDataSourceName(t *testing.T) {
+	statements := sessionStatements(&DSN{Schema: "hydra"})
+	key, err := json.Marshal(&session{Driver: driverOra, DSN: "user/password@ORCL", Statements: statements})
+	if err != nil {
+		t.Fatalf("Could not encode session: %s", err)
+	}
+
+	var got session
+	if err := json.Unmarshal(key, &got); err != nil {
+		t.Fatalf("Could not decode session: %s", err)
+	} else if got.Driver != driverOra || got.DSN != "user/password@ORCL" || len(got.Statements) != len(statements) {
+		t.Fatalf("Expected the session to round trip but got %+v", got)
+	} else if got.Statements[0].query != selectSchema || got.Statements[0].args[0] != "hydra" {
+		t.Fatalf("Expected the schema statement to round trip but got %+v", got.Statements[0])
+	}
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"context"
+
+	"github.com/spf13/viper"
+)
+
+// queryContext returns the context of a single operation of a manager. ORY Hydra does not supply a context to most
+// manager methods and the contexts it does supply usually have no deadline, so contexts without deadline are limited
+// to QUERY_TIMEOUT. A hung Oracle session then fails the operation instead of blocking the request forever.
+func queryContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if _, ok := ctx.Deadline(); ok {
+		return context.WithCancel(ctx)
+	} else if timeout := viper.GetDuration(configQueryTimeout); timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return context.WithCancel(ctx)
+}
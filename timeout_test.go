@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func TestQueryContext(t *testing.T) {
+	defer viper.Set(configQueryTimeout, nil)
+
+	viper.Set(configQueryTimeout, time.Second)
+	ctx, cancel := queryContext(context.Background())
+	defer cancel()
+	if deadline, ok := ctx.Deadline(); !ok || time.Until(deadline) > time.Second {
+		t.Fatalf("Expected the query timeout to be applied but got deadline %s", deadline)
+	}
+
+	parent, cancelParent := context.WithTimeout(context.Background(), time.Hour)
+	defer cancelParent()
+	ctx, cancel = queryContext(parent)
+	defer cancel()
+	if deadline, _ := ctx.Deadline(); time.Until(deadline) < time.Minute {
+		t.Fatalf("Expected the deadline of the supplied context to be kept but got %s", deadline)
+	}
+
+	viper.Set(configQueryTimeout, 0)
+	ctx, cancel = queryContext(context.Background())
+	defer cancel()
+	if _, ok := ctx.Deadline(); ok {
+		t.Fatal("Expected no deadline if the query timeout is disabled")
+	}
+}